@@ -0,0 +1,40 @@
+package goesi
+
+import (
+	"time"
+)
+
+// AllianceInfo is the public information about an alliance
+type AllianceInfo struct {
+	AllianceID            int32     `json:"-"`
+	Name                  string    `json:"name"`
+	Ticker                string    `json:"ticker"`
+	CreatorID             int32     `json:"creator_id"`
+	CreatorCorporationID  int32     `json:"creator_corporation_id"`
+	ExecutorCorporationID int32     `json:"executor_corporation_id"`
+	FactionID             int32     `json:"faction_id"`
+	DateFounded           time.Time `json:"date_founded"`
+}
+
+// UnmarshalJSON decodes the alliance, parsing its founding date with ParseESITime
+func (a *AllianceInfo) UnmarshalJSON(data []byte) error {
+	type allianceInfo AllianceInfo
+	return decodeWithTimes(data, (*allianceInfo)(a), map[string]*time.Time{
+		"date_founded": &a.DateFounded,
+	})
+}
+
+// Alliance fetches the public information about the alliance
+func (e *ESI) Alliance(id int32) (*AllianceInfo, error) {
+	data, err := e.Get("alliances/%d", id)
+	if err != nil {
+		return nil, err
+	}
+	var info AllianceInfo
+	if err := decode(data, &info); err != nil {
+		e.log.Error("Error parsing alliance response", "allianceID", id, "error", err)
+		return nil, err
+	}
+	info.AllianceID = id
+	return &info, nil
+}
@@ -0,0 +1,24 @@
+package goesi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAlliance(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(req.URL.Path, "/alliances/99000001/") {
+			t.Fatalf("Unexpected request to %s", req.URL)
+		}
+		return stubResponse(200, `{"name": "Goesi Alliance", "ticker": "GOES", "creator_id": 90000001, "executor_corporation_id": 98000001, "date_founded": "2018-01-01T00:00:00Z"}`), nil
+	})
+	info, err := e.Alliance(99000001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.AllianceID != 99000001 || info.Name != "Goesi Alliance" || info.Ticker != "GOES" ||
+		info.ExecutorCorporationID != 98000001 || info.DateFounded.Year() != 2018 {
+		t.Fatalf("Unexpected alliance: %+v", info)
+	}
+}
@@ -0,0 +1,139 @@
+package goesi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+var (
+	// ErrInvalidCallbackURL is returned by AuthenticateLocal when ClientCallbackURL
+	// isn't a URL that it can listen on
+	ErrInvalidCallbackURL = errors.New("callback URL can't be served locally")
+	// ErrAuthorizationFailed is returned by AuthenticateLocal when the SSO redirects back
+	// with an error instead of a code, like when the user doesn't give their consent
+	ErrAuthorizationFailed = errors.New("SSO authorization failed")
+)
+
+// callbackResult is what the SSO redirected back to AuthenticateLocal with
+type callbackResult struct {
+	code string
+	err  error
+}
+
+// AuthenticateLocal runs the whole authorization flow for a local app: it listens on
+// the host, port, and path of ClientCallbackURL, calls open with the URL the user must
+// visit (for example, to open it in their browser), and once the SSO redirects back with
+// a code, fetches the access token with Authenticate. It returns when the flow is done
+// or the context is cancelled. If the SSO redirects back with an error, like when the user
+// denies consent, an error wrapping ErrAuthorizationFailed is returned.
+//
+// The authorize URL has a random state, and callbacks without the same state are rejected,
+// so that other requests to the callback URL can't log in as someone else.
+//
+// The SSO only redirects to the callback URL registered for the app, so that's the URL
+// listened on. It must be an http URL on this machine, like "http://localhost:8080/callback";
+// if it isn't, an error wrapping ErrInvalidCallbackURL is returned before anything else is done.
+func (e *ESI) AuthenticateLocal(ctx context.Context, open func(authorizeURL string)) error {
+	addr, path, err := localCallback(e.ClientCallbackURL)
+	if err != nil {
+		return err
+	}
+	authorizeURL, err := e.GetAuthorizeURL()
+	if err != nil {
+		return err
+	}
+	state, err := newState()
+	if err != nil {
+		return err
+	}
+	authorizeURL += "&state=" + state
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("Cannot listen on '%s' for callback URL '%s': %w", addr, e.ClientCallbackURL, err)
+	}
+
+	results := make(chan callbackResult, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		if query.Get("state") != state {
+			e.log.Warn("Rejected a callback without the authorization's state", "url", e.ClientCallbackURL)
+			http.Error(w, "The callback isn't for this login", http.StatusBadRequest)
+			return
+		}
+		var result callbackResult
+		switch {
+		case query.Get("error") != "":
+			result.err = fmt.Errorf("%w: %s: %s", ErrAuthorizationFailed, query.Get("error"), query.Get("error_description"))
+			http.Error(w, "Not logged in: "+query.Get("error"), http.StatusBadRequest)
+		case query.Get("code") == "":
+			http.Error(w, "No code in the callback from the SSO", http.StatusBadRequest)
+			return
+		default:
+			result.code = query.Get("code")
+			fmt.Fprintln(w, "Logged in; you can close this window.")
+		}
+		select {
+		case results <- result:
+		default:
+		}
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+
+	e.log.Info("Waiting for the SSO callback", "url", e.ClientCallbackURL)
+	open(authorizeURL)
+	select {
+	case result := <-results:
+		if result.err != nil {
+			e.log.Warn("SSO authorization failed", "error", result.err)
+			return result.err
+		}
+		return e.Authenticate(result.code)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// newState returns a random value for the state param of an authorization
+func newState() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// localCallback returns the address and path to listen on for the callback URL,
+// or an error if it's not an http URL on this machine
+func localCallback(callback string) (string, string, error) {
+	u, err := url.Parse(callback)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: '%s': %s", ErrInvalidCallbackURL, callback, err)
+	}
+	if u.Scheme != "http" {
+		return "", "", fmt.Errorf("%w: '%s' must use http, not '%s'", ErrInvalidCallbackURL, callback, u.Scheme)
+	}
+	host := u.Hostname()
+	if host != "localhost" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return "", "", fmt.Errorf("%w: '%s' must be on localhost, not '%s'", ErrInvalidCallbackURL, callback, host)
+		}
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	return net.JoinHostPort(host, port), path, nil
+}
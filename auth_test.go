@@ -0,0 +1,114 @@
+package goesi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// authorizeState returns the state param of an authorize URL
+func authorizeState(t *testing.T, authorizeURL string) string {
+	u, err := url.Parse(authorizeURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := u.Query().Get("state")
+	if len(state) < 16 {
+		t.Fatalf("Expected a random state in the authorize URL, got '%s'", authorizeURL)
+	}
+	return state
+}
+
+// getStatus makes a GET request and returns its status code, or 0 if it failed
+func getStatus(u string) int {
+	resp, err := http.Get(u)
+	if err != nil {
+		return 0
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestLocalCallback(t *testing.T) {
+	tests := []struct {
+		callback string
+		addr     string
+		path     string
+		ok       bool
+	}{
+		{"http://localhost:8080/callback", "localhost:8080", "/callback", true},
+		{"http://127.0.0.1/", "127.0.0.1:80", "/", true},
+		{"http://[::1]:9000", "[::1]:9000", "/", true},
+		{"https://localhost:8080/callback", "", "", false},
+		{"http://example.com/callback", "", "", false},
+		{"http://192.168.1.5:8080/callback", "", "", false},
+	}
+	for _, test := range tests {
+		addr, path, err := localCallback(test.callback)
+		if test.ok && (err != nil || addr != test.addr || path != test.path) {
+			t.Fatalf("'%s': expected %s %s, got %s %s %v", test.callback, test.addr, test.path, addr, path, err)
+		}
+		if !test.ok && !errors.Is(err, ErrInvalidCallbackURL) {
+			t.Fatalf("'%s': expected ErrInvalidCallbackURL, got %v", test.callback, err)
+		}
+	}
+}
+
+func TestAuthenticateLocal(t *testing.T) {
+	// find a free port to register as the callback
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if err := req.ParseForm(); err != nil || req.PostForm.Get("code") != "abc" {
+			t.Fatalf("Expected the code from the callback, got %v", req.PostForm)
+		}
+		return stubResponse(200, `{"access_token": "access", "refresh_token": "refresh"}`), nil
+	})
+	e.ClientCallbackURL = "http://" + addr + "/callback"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = e.AuthenticateLocal(ctx, func(authorizeURL string) {
+		state := authorizeState(t, authorizeURL)
+		go func() {
+			// a callback without the state is rejected, and the flow carries on
+			if status := getStatus(e.ClientCallbackURL + "?code=forged"); status != http.StatusBadRequest {
+				t.Errorf("Expected a callback without the state to be rejected, got status %d", status)
+			}
+			getStatus(e.ClientCallbackURL + "?code=abc&state=" + state)
+		}()
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if e.AccessToken != "access" || e.RefreshToken != "refresh" {
+		t.Fatalf("Expected the tokens to be set, got '%s' '%s'", e.AccessToken, e.RefreshToken)
+	}
+
+	// the user denies consent
+	err = e.AuthenticateLocal(ctx, func(authorizeURL string) {
+		state := authorizeState(t, authorizeURL)
+		go getStatus(e.ClientCallbackURL + "?error=access_denied&error_description=The+user+denied+consent&state=" + state)
+	})
+	if !errors.Is(err, ErrAuthorizationFailed) || !strings.Contains(err.Error(), "access_denied") {
+		t.Fatalf("Expected ErrAuthorizationFailed, got %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Expected the denial to be returned without waiting for the context")
+	}
+
+	e.ClientCallbackURL = "https://example.com/callback"
+	if err := e.AuthenticateLocal(ctx, func(string) { t.Fatal("Expected open not to be called") }); !errors.Is(err, ErrInvalidCallbackURL) {
+		t.Fatalf("Expected ErrInvalidCallbackURL, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package goesi
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff decides how long to wait before trying again after a number of failed attempts
+// in a row, starting from 1. Set ESI.Backoff to change how Subscribe backs off after failed
+// checks, for example to wait a constant or linearly growing time.
+type Backoff interface {
+	Next(attempt int) time.Duration
+}
+
+// BackoffFunc is a function that implements Backoff, for strategies that don't need a type
+// of their own:
+//
+//	esi.Backoff = goesi.BackoffFunc(func(attempt int) time.Duration {
+//		return time.Duration(attempt) * 10 * time.Second
+//	})
+type BackoffFunc func(attempt int) time.Duration
+
+// Next calls the function
+func (f BackoffFunc) Next(attempt int) time.Duration {
+	return f(attempt)
+}
+
+// ExponentialBackoff waits Min after the first attempt, doubling the wait after each
+// attempt after that, up to Max. Jitter, from 0 to 1, is how much of each wait is random:
+// with a Jitter of 0.5, a wait of 10s is anywhere from 5s to 10s. Jitter spreads out the
+// retries of many clients that failed at the same time, so they don't all retry at once.
+type ExponentialBackoff struct {
+	Min    time.Duration
+	Max    time.Duration
+	Jitter float64
+}
+
+// DefaultBackoff is the Backoff used when ESI.Backoff isn't set: exponential from five
+// seconds up to five minutes, with up to half of each wait random
+var DefaultBackoff Backoff = ExponentialBackoff{Min: pollMinInterval, Max: pollMaxBackoff, Jitter: 0.5}
+
+// Next returns the wait after the attempt
+func (b ExponentialBackoff) Next(attempt int) time.Duration {
+	wait := b.Max
+	if attempt < 1 {
+		attempt = 1
+	}
+	if attempt < 32 {
+		if backoff := b.Min << uint(attempt-1); backoff > 0 && backoff < wait {
+			wait = backoff
+		}
+	}
+	if b.Jitter > 0 {
+		jitter := b.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		wait -= time.Duration(rand.Float64() * jitter * float64(wait))
+	}
+	return wait
+}
+
+// backoff returns the struct's Backoff, or DefaultBackoff if it isn't set
+func (e *ESI) backoff() Backoff {
+	if e.Backoff == nil {
+		return DefaultBackoff
+	}
+	return e.Backoff
+}
@@ -0,0 +1,107 @@
+package goesi
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without making a request when too many
+// consecutive calls to ESI have failed and the circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open; ESI appears to be unavailable")
+
+// circuitBreaker tracks consecutive ESI failures.
+// Once the failure threshold is reached the circuit opens and requests fail fast
+// until the cooldown has passed, at which point a single request is let through
+// to test whether ESI has recovered (half-open). If that request succeeds the
+// circuit closes again; if it fails the circuit reopens for another cooldown.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	probing  bool
+}
+
+// breakerSet holds a circuit breaker for each host calls are made to, so that a
+// mirror in BaseURLs being down doesn't stop calls to the others
+type breakerSet struct {
+	mu    sync.Mutex
+	hosts map[string]*circuitBreaker
+}
+
+// get returns the circuit breaker for the host, creating it on first use
+func (s *breakerSet) get(host string) *circuitBreaker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hosts == nil {
+		s.hosts = make(map[string]*circuitBreaker)
+	}
+	b, ok := s.hosts[host]
+	if !ok {
+		b = &circuitBreaker{}
+		s.hosts[host] = b
+	}
+	return b
+}
+
+// allow returns an error if the circuit is open and the request should not be made,
+// and whether the request is the one let through to test recovery
+func (b *circuitBreaker) allow(cooldown time.Duration, now time.Time) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return false, nil
+	}
+	if b.probing || now.Sub(b.openedAt) < cooldown {
+		return false, ErrCircuitOpen
+	}
+	b.probing = true
+	return true, nil
+}
+
+// success records a call that ESI answered, closing the circuit.
+// Returns whether the circuit was open.
+func (b *circuitBreaker) success() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasOpen := b.open
+	b.failures = 0
+	b.open = false
+	b.probing = false
+	return wasOpen
+}
+
+// failure records a failed call, opening the circuit once the threshold is reached.
+// Returns the number of consecutive failures and whether the circuit was opened by this call.
+func (b *circuitBreaker) failure(threshold int, now time.Time) (int, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	opened := false
+	if b.probing || b.failures >= threshold {
+		opened = !b.open || b.probing
+		b.open = true
+		b.openedAt = now
+	}
+	b.probing = false
+	return b.failures, opened
+}
+
+// release clears a half-open test request whose outcome says nothing about ESI's health
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// isBreakerFailure returns whether the result of a request counts towards opening the circuit
+func isBreakerFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		return errors.As(err, &netErr) && netErr.Timeout()
+	}
+	return resp.StatusCode >= 500
+}
@@ -1,8 +1,13 @@
 package goesi
 
 import (
+	"container/list"
+	"encoding/json"
+	"fmt"
 	"github.com/Jeffail/gabs"
+	"math/rand"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -10,46 +15,414 @@ import (
 type CacheEntry struct {
 	Data    *gabs.Container
 	Expires time.Time
+	ETag    string
+	Header  http.Header
 }
 
-// A Cache is a map that stores GET responses from ESI.
-// This cache is for for responses to GET requests only - POST
-// requests are not cached, as the responses are likely determined
-// by what is sent to ESI.
-type Cache map[string]CacheEntry
+// CacheStats counts how calls have been served
+type CacheStats struct {
+	// Hits is the number of calls served from the cache without a request to ESI
+	Hits int64
+	// ConditionalHits is the number of calls where a request was made, but ESI
+	// responded 304 Not Modified and the cached data was reused
+	ConditionalHits int64
+	// Misses is the number of calls where the full response was fetched from ESI
+	Misses int64
+}
+
+// CacheEventType is what happened in a CacheEvent
+type CacheEventType string
+
+// The types of CacheEvent
+const (
+	// CacheHit is a call served from the cache without a request to ESI
+	CacheHit CacheEventType = "hit"
+	// CacheMiss is a call with nothing in the cache for it, so a request is made
+	CacheMiss CacheEventType = "miss"
+	// CacheExpired is a call whose cached response has expired, so a request is made
+	// (or, for GetStaleOK, the expired response is returned and refreshed in the background)
+	CacheExpired CacheEventType = "expired"
+	// CacheStore is a response stored in the cache, including a cached response that ESI
+	// said was unchanged, which is stored again with its new expiry
+	CacheStore CacheEventType = "store"
+)
+
+// A CacheEvent describes something the cache did for a single call, for ESI.OnCache
+type CacheEvent struct {
+	Type   CacheEventType
+	Method string
+	URL    string
+	// Time is when it happened, by the ESI struct's clock
+	Time time.Time
+	// Expires is when the cached response expires, or expired. It's the zero time for
+	// misses and for cached 404 responses.
+	Expires time.Time
+	// Duration is how long the request took, for stored responses
+	Duration time.Duration
+}
+
+// A Cache stores GET responses from ESI. It is safe for concurrent use.
+// POST requests are not cached by default, as the responses are likely
+// determined by what is sent to ESI and the request may change data.
+// If ESI.CachePOST is set, POST responses are also stored, keyed on the
+// URL and a hash of the request body.
+//
+// Set MaxEntries, OnEvict, and ExpiryJitter before the cache is used.
+type Cache struct {
+	// MaxEntries is the most entries the cache holds. When it's full, expired
+	// entries are evicted first, then the least recently used. 0 means no limit.
+	MaxEntries int
+	// OnEvict, if set, is called with each entry evicted because the cache was full
+	// or by Prune. It is not called for entries dropped by invalidation.
+	OnEvict func(url string, entry CacheEntry)
+	// ExpiryJitter shortens the expiry of each stored response by a random amount up to it,
+	// so that responses that ESI gave the same expiry, as it often does, don't all expire
+	// and get fetched again at once. 0 means responses expire exactly when ESI says.
+	ExpiryJitter time.Duration
+
+	clock   Clock
+	log     Logger
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+	// order has the URLs of the entries, most recently used at the front
+	order *list.List
+	elems map[string]*list.Element
+	stats CacheStats
+	// missing has the 404 responses, when ESI.CacheNotFound is set. They aren't counted
+	// towards MaxEntries; expired ones are dropped when they're next looked up, or by Prune.
+	missing map[string]notFoundEntry
+}
+
+// notFoundEntry is a cached 404 response
+type notFoundEntry struct {
+	err     *ESIError
+	expires time.Time
+}
+
+// evicted is an entry that was evicted while the lock was held, to pass to OnEvict after
+type evicted struct {
+	url   string
+	entry CacheEntry
+}
+
+// newCache creates an empty cache
+func newCache() *Cache {
+	return &Cache{
+		log:     defaultLogger,
+		entries: make(map[string]CacheEntry),
+		order:   list.New(),
+		elems:   make(map[string]*list.Element),
+		missing: make(map[string]notFoundEntry),
+	}
+}
 
 // get returns an entry from the map (if it exists and is not expired).
-// If the entry is present but expired, it is removed from the map.
+// Expired entries are kept so that they can still be served by GetStaleOK;
+// they are replaced when their URL is next fetched.
 func (c *Cache) get(u string) *gabs.Container {
-	entry, ok := (*c)[u]
+	entry, ok := c.getEntry(u)
 	if !ok {
-		log.Debug("No entry in cache for URL '%s'", u)
 		return nil
 	}
+	return entry.Data
+}
+
+// getEntry is like get, but returns the whole entry
+func (c *Cache) getEntry(u string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[u]
+	if !ok {
+		c.log.Debug("No entry in cache", "url", u, "cacheHit", false)
+		return CacheEntry{}, false
+	}
 	// check expiration
-	log.Debug("Checking expiration value")
-	if entry.Expires.Before(time.Now().UTC()) {
-		// removed the expired data from the cache
-		log.Debug("Data in cache is expired; removing from cache")
-		delete(*c, u)
+	if entry.Expires.Before(now(c.clock)) {
+		c.log.Debug("Data in cache is expired", "url", u, "expires", entry.Expires, "cacheHit", false)
+		return CacheEntry{}, false
+	}
+	c.log.Debug("Returning non-expired cached data", "url", u, "expires", entry.Expires, "cacheHit", true)
+	c.stats.Hits++
+	c.touch(u)
+	return entry, true
+}
+
+// stale returns the data for an entry from the map whether or not it has expired
+func (c *Cache) stale(u string) *gabs.Container {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[u]
+	if !ok {
 		return nil
 	}
-	log.Debug("Returning non-expired cached data")
 	return entry.Data
 }
 
-// set puts the url and its data into the cache
-func (c *Cache) set(u string, d *gabs.Container, h http.Header) error {
-	expires, err := getExpiration(h.Get("Expires"))
-	log.Debug("Storing url in cache, '%s', expires '%s'", u, expires)
+// expires returns the expiration time of the entry for the url, if there is one
+func (c *Cache) expires(u string) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[u]
+	if !ok {
+		return time.Time{}, false
+	}
+	return entry.Expires, true
+}
+
+// set puts the url and its data into the cache.
+// If ttl is set, it is used as the expiration instead of the response's Expires header.
+func (c *Cache) set(u string, d *gabs.Container, h http.Header, ttl time.Duration) error {
+	expires, err := expiration(h, ttl, now(c.clock))
 	if err != nil {
+		c.log.Debug("Not storing response without an expiration in cache", "url", u, "error", err)
 		return err
 	}
-	entry := CacheEntry{d, expires}
-	(*c)[u] = entry
+	expires = c.jitter(expires)
+	c.log.Debug("Storing response in cache", "url", u, "expires", expires)
+	c.mu.Lock()
+	delete(c.missing, u)
+	c.entries[u] = CacheEntry{d, expires, h.Get("ETag"), h}
+	c.touch(u)
+	evictions := c.evictOverflow()
+	c.mu.Unlock()
+	c.notifyEvicted(evictions)
 	return nil
 }
 
+// jitter returns the expiry shortened by a random amount up to ExpiryJitter, but not to before now
+func (c *Cache) jitter(expires time.Time) time.Time {
+	window := c.ExpiryJitter
+	if untilExpiry := expires.Sub(now(c.clock)); untilExpiry < window {
+		window = untilExpiry
+	}
+	if window <= 0 {
+		return expires
+	}
+	return expires.Add(-time.Duration(rand.Int63n(int64(window))))
+}
+
+// touch marks the entry for the url as the most recently used. The lock must be held.
+func (c *Cache) touch(u string) {
+	if elem, ok := c.elems[u]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.elems[u] = c.order.PushFront(u)
+}
+
+// evictOverflow evicts entries until the cache is within MaxEntries, starting with
+// expired entries and then the least recently used. The lock must be held.
+func (c *Cache) evictOverflow() []evicted {
+	if c.MaxEntries <= 0 || len(c.entries) <= c.MaxEntries {
+		return nil
+	}
+	var evictions []evicted
+	current := now(c.clock)
+	for elem := c.order.Back(); elem != nil && len(c.entries) > c.MaxEntries; {
+		prev := elem.Prev()
+		u := elem.Value.(string)
+		if c.entries[u].Expires.Before(current) {
+			evictions = append(evictions, evicted{u, c.entries[u]})
+			c.delete(u)
+		}
+		elem = prev
+	}
+	for len(c.entries) > c.MaxEntries {
+		u := c.order.Back().Value.(string)
+		evictions = append(evictions, evicted{u, c.entries[u]})
+		c.delete(u)
+	}
+	c.log.Debug("Evicted entries from the cache", "count", len(evictions))
+	return evictions
+}
+
+// Prune evicts all expired entries from the cache. Expired entries are otherwise
+// kept until the cache is full, so that they can be served by GetStaleOK or
+// revalidated with their ETag.
+func (c *Cache) Prune() {
+	c.mu.Lock()
+	var evictions []evicted
+	current := now(c.clock)
+	for u, entry := range c.entries {
+		if entry.Expires.Before(current) {
+			evictions = append(evictions, evicted{u, entry})
+			c.delete(u)
+		}
+	}
+	for u, entry := range c.missing {
+		if entry.expires.Before(current) {
+			delete(c.missing, u)
+		}
+	}
+	c.mu.Unlock()
+	c.notifyEvicted(evictions)
+}
+
+// notifyEvicted passes the evicted entries to OnEvict. The lock must not be held.
+func (c *Cache) notifyEvicted(evictions []evicted) {
+	if c.OnEvict == nil {
+		return
+	}
+	for _, e := range evictions {
+		c.OnEvict(e.url, e.entry)
+	}
+}
+
+// delete drops the entry for the url. The lock must be held.
+func (c *Cache) delete(u string) {
+	delete(c.entries, u)
+	delete(c.missing, u)
+	if elem, ok := c.elems[u]; ok {
+		c.order.Remove(elem)
+		delete(c.elems, u)
+	}
+}
+
+// etag returns the ETag of the entry for the url, or an empty string if there isn't one
+func (c *Cache) etag(u string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries[u].ETag
+}
+
+// revalidate updates the expiration of the entry for the url after ESI has responded
+// that it is not modified, and returns the entry. Returns false if there is no entry.
+func (c *Cache) revalidate(u string, h http.Header, ttl time.Duration) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[u]
+	if !ok {
+		return CacheEntry{}, false
+	}
+	if expires, err := expiration(h, ttl, now(c.clock)); err == nil {
+		entry.Expires = c.jitter(expires)
+	}
+	if etag := h.Get("ETag"); etag != "" {
+		entry.ETag = etag
+	}
+	c.entries[u] = entry
+	c.stats.ConditionalHits++
+	c.touch(u)
+	return entry, true
+}
+
+// notFound returns a copy of the cached 404 error for the url, if there's one that hasn't expired
+func (c *Cache) notFound(u string) (*ESIError, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.missing[u]
+	if !ok {
+		return nil, false
+	}
+	if entry.expires.Before(now(c.clock)) {
+		delete(c.missing, u)
+		return nil, false
+	}
+	c.stats.Hits++
+	err := *entry.err
+	return &err, true
+}
+
+// setNotFound caches the 404 error for the url for the ttl
+func (c *Cache) setNotFound(u string, err *ESIError, ttl time.Duration) {
+	c.log.Debug("Storing not found response in cache", "url", u, "ttl", ttl)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.missing[u] = notFoundEntry{err, now(c.clock).Add(ttl)}
+}
+
+// countMiss records a call where the full response had to be fetched
+func (c *Cache) countMiss() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Misses++
+}
+
+// Stats returns the counts of how calls have been served
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// snapshotVersion is the version of the format Snapshot writes, so that RestoreCache
+// can reject snapshots it doesn't understand
+const snapshotVersion = 1
+
+// cacheSnapshot is the format of a cache snapshot
+type cacheSnapshot struct {
+	Version int `json:"version"`
+	// Entries are in order from least to most recently used
+	Entries []snapshotEntry `json:"entries"`
+}
+
+// snapshotEntry is a cache entry in a snapshot
+type snapshotEntry struct {
+	Key     string          `json:"key"`
+	Data    json.RawMessage `json:"data"`
+	Expires time.Time       `json:"expires"`
+	ETag    string          `json:"etag,omitempty"`
+	Header  http.Header     `json:"header,omitempty"`
+}
+
+// Snapshot returns the cache's entries, their keys, data, expiries, ETags, and headers,
+// as a blob that RestoreCache turns back into a cache, for keeping the cache across
+// restarts wherever suits, like in a database. Expired entries are kept, as they can
+// still be revalidated with their ETags. The cached 404 responses, the stats, and the
+// settings, like MaxEntries, aren't included.
+func (c *Cache) Snapshot() ([]byte, error) {
+	c.mu.Lock()
+	snapshot := cacheSnapshot{Version: snapshotVersion, Entries: make([]snapshotEntry, 0, len(c.entries))}
+	for elem := c.order.Back(); elem != nil; elem = elem.Prev() {
+		key := elem.Value.(string)
+		entry, ok := c.entries[key]
+		if !ok {
+			continue
+		}
+		snapshot.Entries = append(snapshot.Entries, snapshotEntry{key, entry.Data.Bytes(), entry.Expires, entry.ETag, entry.Header})
+	}
+	c.mu.Unlock()
+	return json.Marshal(snapshot)
+}
+
+// RestoreCache creates a cache from a blob returned by Snapshot. Set it on an ESI struct
+// with SetCache. The entries keep their expiries, so those that expired in the meantime
+// are fetched again, or revalidated, when they're next used.
+func RestoreCache(data []byte) (*Cache, error) {
+	var snapshot cacheSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	if snapshot.Version != snapshotVersion {
+		return nil, fmt.Errorf("Unsupported cache snapshot version %d", snapshot.Version)
+	}
+	c := newCache()
+	for _, entry := range snapshot.Entries {
+		parsed, err := gabs.ParseJSON(entry.Data)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot parse cached data for '%s': %w", entry.Key, err)
+		}
+		c.entries[entry.Key] = CacheEntry{parsed, entry.Expires, entry.ETag, entry.Header}
+		c.touch(entry.Key)
+	}
+	return c, nil
+}
+
+// remove drops the entry for the url from the cache
+func (c *Cache) remove(u string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.delete(u)
+}
+
+// expiration returns when a response expires: after the ttl if it's set, otherwise per the Expires header
+func expiration(h http.Header, ttl time.Duration, now time.Time) (time.Time, error) {
+	if ttl > 0 {
+		return now.UTC().Add(ttl), nil
+	}
+	return getExpiration(h.Get("Expires"))
+}
+
 // getExpiration parses the expiration time from the ESI response headers
 func getExpiration(s string) (time.Time, error) {
 	parseFormat := "Mon, 02 Jan 2006 15:04:05 MST"
@@ -59,3 +432,34 @@ func getExpiration(s string) (time.Time, error) {
 	}
 	return t, nil
 }
+
+// cacheEvent calls OnCache with the event, if it's set
+func (e *ESI) cacheEvent(typ CacheEventType, method, url string, expires time.Time, duration time.Duration) {
+	if e.OnCache == nil {
+		return
+	}
+	e.OnCache(CacheEvent{Type: typ, Method: method, URL: url, Time: e.now(), Expires: expires, Duration: duration})
+}
+
+// cacheMissEvent calls OnCache for a call to the URL that wasn't served from the cache,
+// as a CacheExpired event if there's an expired entry for it, otherwise as a CacheMiss
+func (e *ESI) cacheMissEvent(method, url, key string) {
+	if e.OnCache == nil {
+		return
+	}
+	if expires, ok := e.cache.expires(key); ok {
+		e.cacheEvent(CacheExpired, method, url, expires, 0)
+		return
+	}
+	e.cacheEvent(CacheMiss, method, url, time.Time{}, 0)
+}
+
+// cacheStoreEvent calls OnCache for a response to the URL stored in the cache under the key
+func (e *ESI) cacheStoreEvent(method, url, key string, duration time.Duration) {
+	if e.OnCache == nil {
+		return
+	}
+	if expires, ok := e.cache.expires(key); ok {
+		e.cacheEvent(CacheStore, method, url, expires, duration)
+	}
+}
@@ -1,6 +1,10 @@
 package goesi
 
 import (
+	"errors"
+	"fmt"
+	"github.com/Jeffail/gabs"
+	"net/http"
 	"testing"
 	"time"
 )
@@ -20,3 +24,206 @@ func TestGetExpiration(t *testing.T) {
 		t.Fatalf("Dates are not equal. Expected: %s, actual: %s", expected, e)
 	}
 }
+
+func TestCacheEviction(t *testing.T) {
+	c := newCache()
+	c.MaxEntries = 2
+	var evictedURLs []string
+	c.OnEvict = func(url string, entry CacheEntry) {
+		evictedURLs = append(evictedURLs, url)
+	}
+	past := http.Header{"Expires": []string{time.Now().UTC().Add(-time.Hour).Format(http.TimeFormat)}}
+	future := http.Header{"Expires": []string{time.Now().UTC().Add(time.Hour).Format(http.TimeFormat)}}
+	c.set("a", gabs.New(), future, 0)
+	c.set("b", gabs.New(), past, 0)
+	c.set("c", gabs.New(), future, 0)
+	// b is expired so it goes first, even though a is older
+	c.get("a")
+	c.set("d", gabs.New(), future, 0)
+	// c is now the least recently used
+	if len(evictedURLs) != 2 || evictedURLs[0] != "b" || evictedURLs[1] != "c" {
+		t.Fatalf("Expected b then c to be evicted, got %v", evictedURLs)
+	}
+	if c.get("a") == nil || c.get("d") == nil {
+		t.Fatal("Expected a and d to still be cached")
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	c := newCache()
+	evictedEntries := make(map[string]CacheEntry)
+	c.OnEvict = func(url string, entry CacheEntry) {
+		evictedEntries[url] = entry
+	}
+	past := http.Header{"Expires": []string{time.Now().UTC().Add(-time.Hour).Format(http.TimeFormat)}}
+	past.Set("ETag", `"old"`)
+	future := http.Header{"Expires": []string{time.Now().UTC().Add(time.Hour).Format(http.TimeFormat)}}
+	c.set("expired", gabs.New(), past, 0)
+	c.set("fresh", gabs.New(), future, 0)
+	if c.get("expired") != nil || c.stale("expired") == nil || len(evictedEntries) != 0 {
+		t.Fatal("Expected the expired entry to be kept until it's pruned")
+	}
+	c.Prune()
+	if len(evictedEntries) != 1 || evictedEntries["expired"].ETag != `"old"` {
+		t.Fatalf("Expected only the expired entry to be evicted, got %v", evictedEntries)
+	}
+	if c.stale("expired") != nil || c.get("fresh") == nil {
+		t.Fatal("Expected the expired entry to be dropped and the fresh one kept")
+	}
+}
+
+func TestCacheNotFound(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)}
+	calls := 0
+	status := 404
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if status == 404 {
+			return stubResponse(404, `{"error": "Character not found"}`), nil
+		}
+		return stubResponse(200, `{"name": "Goesi Pilot"}`, "Expires", clock.now.Add(time.Hour).Format(http.TimeFormat)), nil
+	})
+	e.SetClock(clock)
+	e.CacheNotFound = true
+	e.NotFoundTTL = 30 * time.Second
+	for i := 0; i < 3; i++ {
+		_, err := e.Get("characters/%d", 1)
+		var esiErr *ESIError
+		if !errors.As(err, &esiErr) || esiErr.StatusCode != 404 || esiErr.Message != "Character not found" {
+			t.Fatalf("Expected the 404 error, got %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("Expected the 404 to be cached, made %d requests", calls)
+	}
+	clock.now = clock.now.Add(time.Minute)
+	status = 200
+	if _, err := e.Get("characters/%d", 1); err != nil {
+		t.Fatalf("Expected the cached 404 to expire, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("Expected a request once the 404 expired, made %d requests", calls)
+	}
+
+	e.CacheNotFound = false
+	status = 404
+	e.Get("characters/%d", 2)
+	e.Get("characters/%d", 2)
+	if calls != 4 {
+		t.Fatalf("Expected 404s not to be cached by default, made %d requests", calls)
+	}
+}
+
+func TestOnCache(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)}
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		expires := clock.now.Add(time.Minute).Format(http.TimeFormat)
+		if req.Header.Get("If-None-Match") != "" {
+			return stubResponse(304, "", "ETag", `"a"`, "Expires", expires), nil
+		}
+		return stubResponse(200, `{"players": 30000}`, "ETag", `"a"`, "Expires", expires), nil
+	})
+	e.SetClock(clock)
+	var events []CacheEvent
+	e.OnCache = func(event CacheEvent) {
+		events = append(events, event)
+	}
+	e.Get("status")
+	e.Get("status")
+	clock.now = clock.now.Add(2 * time.Minute)
+	e.Get("status")
+	e.Get("status", NoCache())
+
+	expected := []CacheEventType{CacheMiss, CacheStore, CacheHit, CacheExpired, CacheStore, CacheStore}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected events %v, got %+v", expected, events)
+	}
+	for i, event := range events {
+		if event.Type != expected[i] || event.Method != "GET" || event.URL != BaseURL+"latest/status/" {
+			t.Fatalf("Event %d: expected a %s for the status route, got %+v", i, expected[i], event)
+		}
+	}
+	if !events[1].Expires.Equal(events[0].Time.Add(time.Minute)) || !events[2].Expires.Equal(events[1].Expires) {
+		t.Fatalf("Expected the stored response's expiry, got %+v", events[1:3])
+	}
+	if !events[3].Expires.Before(events[3].Time) {
+		t.Fatalf("Expected the expired response's expiry, got %+v", events[3])
+	}
+}
+
+func TestCacheSnapshot(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)}
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			return stubResponse(304, "", "Expires", clock.now.Add(time.Hour).Format(http.TimeFormat)), nil
+		}
+		return stubResponse(200, `{"players": 30000}`, "Expires", clock.now.Add(time.Minute).Format(http.TimeFormat), "ETag", `"v1"`), nil
+	})
+	e.SetClock(clock)
+	e.Get("status")
+	e.Get("markets/prices")
+
+	data, err := e.Cache().Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := RestoreCache(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetCache(restored)
+	calls = 0
+	json, err := e.Get("status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 || json.Path("players").Data().(float64) != 30000 {
+		t.Fatalf("Expected the restored entry to be served, got %s after %d calls", json, calls)
+	}
+
+	clock.now = clock.now.Add(2 * time.Minute)
+	if _, err := e.Get("markets/prices"); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || e.Stats().ConditionalHits != 1 {
+		t.Fatalf("Expected the expired restored entry to be revalidated with its ETag, got %d calls and %+v", calls, e.Stats())
+	}
+
+	if _, err := RestoreCache([]byte(`{"version": 99}`)); err == nil {
+		t.Fatal("Expected an error for an unknown snapshot version")
+	}
+}
+
+func TestCacheExpiryJitter(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)}
+	c := newCache()
+	c.clock = clock
+	c.ExpiryJitter = time.Minute
+	header := http.Header{"Expires": {clock.now.Add(5 * time.Minute).Format(http.TimeFormat)}}
+	expiries := make(map[time.Time]bool)
+	for i := 0; i < 20; i++ {
+		url := fmt.Sprintf("https://esi.evetech.net/latest/characters/%d/", i)
+		c.set(url, gabs.New(), header, 0)
+		expires, _ := c.expires(url)
+		if expires.After(clock.now.Add(5*time.Minute)) || expires.Before(clock.now.Add(4*time.Minute)) {
+			t.Fatalf("Expected the expiry to be shortened by up to a minute, got %s", expires)
+		}
+		expiries[expires] = true
+	}
+	if len(expiries) < 2 {
+		t.Fatal("Expected the expiries to be spread out")
+	}
+
+	soon := http.Header{"Expires": {clock.now.Add(10 * time.Second).Format(http.TimeFormat)}}
+	c.set("soon", gabs.New(), soon, 0)
+	if expires, _ := c.expires("soon"); expires.Before(clock.now) {
+		t.Fatalf("Expected the expiry not to be moved before now, got %s", expires)
+	}
+	c.ExpiryJitter = 0
+	c.set("exact", gabs.New(), header, 0)
+	if expires, _ := c.expires("exact"); !expires.Equal(clock.now.Add(5 * time.Minute)) {
+		t.Fatalf("Expected the exact expiry without jitter, got %s", expires)
+	}
+}
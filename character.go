@@ -0,0 +1,273 @@
+package goesi
+
+import (
+	"fmt"
+	"time"
+)
+
+// CharacterStatus is where a character is, what they're flying, and whether they're online
+type CharacterStatus struct {
+	SolarSystemID int32     `json:"solar_system_id"`
+	StationID     int32     `json:"station_id"`
+	StructureID   int64     `json:"structure_id"`
+	Online        bool      `json:"online"`
+	LastLogin     time.Time `json:"last_login"`
+	LastLogout    time.Time `json:"last_logout"`
+	Logins        int32     `json:"logins"`
+	ShipTypeID    int32     `json:"ship_type_id"`
+	ShipItemID    int64     `json:"ship_item_id"`
+	ShipName      string    `json:"ship_name"`
+}
+
+// UnmarshalJSON decodes the status, parsing its login and logout times with ParseESITime
+func (s *CharacterStatus) UnmarshalJSON(data []byte) error {
+	type characterStatus CharacterStatus
+	return decodeWithTimes(data, (*characterStatus)(s), map[string]*time.Time{
+		"last_login":  &s.LastLogin,
+		"last_logout": &s.LastLogout,
+	})
+}
+
+// CharacterStatus fetches the character's location, online status, and current ship
+// at the same time and returns them combined. The access token needs the
+// esi-location.read_location.v1, esi-location.read_online.v1, and
+// esi-location.read_ship_type.v1 scopes.
+func (e *ESI) CharacterStatus(id int32) (*CharacterStatus, error) {
+	responses, err := e.getAll([]string{
+		fmt.Sprintf("characters/%d/location", id),
+		fmt.Sprintf("characters/%d/online", id),
+		fmt.Sprintf("characters/%d/ship", id),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the three responses have no fields in common, so they all decode into the one struct
+	var status CharacterStatus
+	for _, response := range responses {
+		if err := decode(response, &status); err != nil {
+			e.log.Error("Error parsing character status response", "characterID", id, "error", err)
+			return nil, err
+		}
+	}
+	return &status, nil
+}
+
+// Asset is an item owned by a character
+type Asset struct {
+	ItemID          int64  `json:"item_id"`
+	TypeID          int32  `json:"type_id"`
+	LocationID      int64  `json:"location_id"`
+	LocationFlag    string `json:"location_flag"`
+	LocationType    string `json:"location_type"`
+	Quantity        int32  `json:"quantity"`
+	IsSingleton     bool   `json:"is_singleton"`
+	IsBlueprintCopy bool   `json:"is_blueprint_copy"`
+}
+
+// CharacterAssets fetches all pages of the character's assets. If flags are given, only
+// assets with one of those location flags (like "Hangar" or "Cargo") are returned.
+// The access token needs the esi-assets.read_assets.v1 scope.
+func (e *ESI) CharacterAssets(characterID int32, flags []string) ([]Asset, error) {
+	data, err := e.GetAllPages("characters/%d/assets", characterID)
+	if err != nil {
+		return nil, err
+	}
+	var assets []Asset
+	if err := decode(data, &assets); err != nil {
+		e.log.Error("Error parsing assets response", "characterID", characterID, "error", err)
+		return nil, err
+	}
+	if len(flags) == 0 {
+		return assets, nil
+	}
+	filtered := assets[:0]
+	for _, asset := range assets {
+		if containsString(flags, asset.LocationFlag) {
+			filtered = append(filtered, asset)
+		}
+	}
+	return filtered, nil
+}
+
+// Notification is one of a character's notifications. Text is the notification's
+// YAML body, left as-is; its fields depend on the Type.
+type Notification struct {
+	NotificationID int64     `json:"notification_id"`
+	Type           string    `json:"type"`
+	SenderID       int32     `json:"sender_id"`
+	SenderType     string    `json:"sender_type"`
+	Timestamp      time.Time `json:"timestamp"`
+	IsRead         bool      `json:"is_read"`
+	Text           string    `json:"text"`
+}
+
+// UnmarshalJSON decodes the notification, parsing its timestamp with ParseESITime
+func (n *Notification) UnmarshalJSON(data []byte) error {
+	type notification Notification
+	return decodeWithTimes(data, (*notification)(n), map[string]*time.Time{
+		"timestamp": &n.Timestamp,
+	})
+}
+
+// Notifications fetches the character's notifications. The access token needs the
+// esi-characters.read_notifications.v1 scope.
+func (e *ESI) Notifications(characterID int32) ([]Notification, error) {
+	data, err := e.Get("characters/%d/notifications", characterID)
+	if err != nil {
+		return nil, err
+	}
+	var notifications []Notification
+	if err := decode(data, &notifications); err != nil {
+		e.log.Error("Error parsing notifications response", "characterID", characterID, "error", err)
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// Portraits are the URLs of a character's portrait in each of the sizes ESI has
+type Portraits struct {
+	Px64  string `json:"px64x64"`
+	Px128 string `json:"px128x128"`
+	Px256 string `json:"px256x256"`
+	Px512 string `json:"px512x512"`
+}
+
+// CharacterPortraits fetches the URLs of the character's portrait, at 64, 128, 256,
+// and 512 pixels square. The route is public, so the access token isn't sent, and
+// the response is cached like any other.
+func (e *ESI) CharacterPortraits(id int32) (*Portraits, error) {
+	data, err := e.GetPublic("characters/%d/portrait", id)
+	if err != nil {
+		return nil, err
+	}
+	var portraits Portraits
+	if err := decode(data, &portraits); err != nil {
+		e.log.Error("Error parsing portrait response", "characterID", id, "error", err)
+		return nil, err
+	}
+	return &portraits, nil
+}
+
+// Skill is a skill a character has injected, with the level they can use it at
+// (ActiveLevel, lower than TrainedLevel for Omega skills on an Alpha clone)
+type Skill struct {
+	SkillID      int32 `json:"skill_id"`
+	ActiveLevel  int32 `json:"active_skill_level"`
+	TrainedLevel int32 `json:"trained_skill_level"`
+	Skillpoints  int64 `json:"skillpoints_in_skill"`
+}
+
+// Attributes are a character's attributes and when they can next remap them
+type Attributes struct {
+	Charisma                 int32     `json:"charisma"`
+	Intelligence             int32     `json:"intelligence"`
+	Memory                   int32     `json:"memory"`
+	Perception               int32     `json:"perception"`
+	Willpower                int32     `json:"willpower"`
+	BonusRemaps              int32     `json:"bonus_remaps"`
+	LastRemapDate            time.Time `json:"last_remap_date"`
+	AccruedRemapCooldownDate time.Time `json:"accrued_remap_cooldown_date"`
+}
+
+// UnmarshalJSON decodes the attributes, parsing their dates with ParseESITime
+func (a *Attributes) UnmarshalJSON(data []byte) error {
+	type attributes Attributes
+	return decodeWithTimes(data, (*attributes)(a), map[string]*time.Time{
+		"last_remap_date":             &a.LastRemapDate,
+		"accrued_remap_cooldown_date": &a.AccruedRemapCooldownDate,
+	})
+}
+
+// CloneLocation is where a clone is, a station or a structure
+type CloneLocation struct {
+	LocationID   int64  `json:"location_id"`
+	LocationType string `json:"location_type"`
+}
+
+// JumpClone is one of a character's jump clones and the implants in it
+type JumpClone struct {
+	JumpCloneID  int32   `json:"jump_clone_id"`
+	Name         string  `json:"name"`
+	LocationID   int64   `json:"location_id"`
+	LocationType string  `json:"location_type"`
+	Implants     []int32 `json:"implants"`
+}
+
+// Clones are a character's home station and jump clones
+type Clones struct {
+	HomeLocation          CloneLocation `json:"home_location"`
+	JumpClones            []JumpClone   `json:"jump_clones"`
+	LastCloneJumpDate     time.Time     `json:"last_clone_jump_date"`
+	LastStationChangeDate time.Time     `json:"last_station_change_date"`
+}
+
+// UnmarshalJSON decodes the clones, parsing their dates with ParseESITime
+func (c *Clones) UnmarshalJSON(data []byte) error {
+	type clones Clones
+	return decodeWithTimes(data, (*clones)(c), map[string]*time.Time{
+		"last_clone_jump_date":     &c.LastCloneJumpDate,
+		"last_station_change_date": &c.LastStationChangeDate,
+	})
+}
+
+// CharacterSheet is a character's skills, attributes, and clones
+type CharacterSheet struct {
+	Skills        []Skill    `json:"skills"`
+	TotalSP       int64      `json:"total_sp"`
+	UnallocatedSP int32      `json:"unallocated_sp"`
+	Attributes    Attributes `json:"-"`
+	Clones        Clones     `json:"-"`
+}
+
+// CharacterSheet fetches the character's skills, attributes, and clones at the same time
+// and returns them combined. The access token needs the esi-skills.read_skills.v1 and
+// esi-clones.read_clones.v1 scopes.
+func (e *ESI) CharacterSheet(characterID int32) (*CharacterSheet, error) {
+	responses, err := e.getAll([]string{
+		fmt.Sprintf("characters/%d/skills", characterID),
+		fmt.Sprintf("characters/%d/attributes", characterID),
+		fmt.Sprintf("characters/%d/clones", characterID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var sheet CharacterSheet
+	for i, v := range []interface{}{&sheet, &sheet.Attributes, &sheet.Clones} {
+		if err := decode(responses[i], v); err != nil {
+			e.log.Error("Error parsing character sheet response", "characterID", characterID, "error", err)
+			return nil, err
+		}
+	}
+	return &sheet, nil
+}
+
+// warmCharacterPaths are the routes a character's page usually shows, for WarmCharacter
+var warmCharacterPaths = []string{
+	"characters/%d",
+	"characters/%d/portrait",
+	"characters/%d/corporationhistory",
+	"characters/%d/location",
+	"characters/%d/ship",
+	"characters/%d/online",
+}
+
+// WarmCharacter fetches what a character's page usually shows into the cache, all at the
+// same time, so that showing the page is quick: their public information, portrait, and
+// corporation history, and where they are, what they're flying, and whether they're online.
+// Those last need scopes, and are skipped if there's no access token or it wasn't granted
+// them. If any of the calls fail, the rest are still cached, and a *MultiError holding the
+// errors is returned.
+func (e *ESI) WarmCharacter(characterID int32) error {
+	granted := e.GrantedScopes()
+	paths := make([]string, 0, len(warmCharacterPaths))
+	for _, path := range warmCharacterPaths {
+		path = fmt.Sprintf(path, characterID)
+		if scope, ok := RequiredScope("GET", path); ok && !containsString(granted, scope) {
+			e.log.Debug("Not warming route without its scope", "path", path, "scope", scope)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	_, err := e.GetMany(paths)
+	return err
+}
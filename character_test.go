@@ -0,0 +1,159 @@
+package goesi
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNotifications(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		return stubResponse(200, `[{"notification_id": 123, "type": "StructureUnderAttack", "sender_id": 1000125, "sender_type": "corporation", "timestamp": "2020-01-02T03:04:05Z", "is_read": true, "text": "allianceID: 99000001\nshieldPercentage: 94.5\n"}]`), nil
+	})
+	notifications, err := e.Notifications(90000001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifications) != 1 {
+		t.Fatalf("Expected 1 notification, got %d", len(notifications))
+	}
+	n := notifications[0]
+	if n.NotificationID != 123 || n.Type != "StructureUnderAttack" || n.SenderType != "corporation" || !n.IsRead || n.Timestamp.Year() != 2020 {
+		t.Fatalf("Unexpected notification: %+v", n)
+	}
+	if n.Text != "allianceID: 99000001\nshieldPercentage: 94.5\n" {
+		t.Fatalf("Expected the raw text, got '%s'", n.Text)
+	}
+}
+
+func TestCharacterStatus(t *testing.T) {
+	var calls int32
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/characters/90000001/location/"):
+			return stubResponse(200, `{"solar_system_id": 30000142, "station_id": 60003760}`), nil
+		case strings.HasSuffix(req.URL.Path, "/characters/90000001/online/"):
+			return stubResponse(200, `{"online": true, "logins": 42, "last_login": "2020-01-02T03:04:05Z"}`), nil
+		case strings.HasSuffix(req.URL.Path, "/characters/90000001/ship/"):
+			return stubResponse(200, `{"ship_type_id": 587, "ship_item_id": 1000000016991, "ship_name": "Rifter"}`), nil
+		}
+		t.Fatalf("Unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	status, err := e.CharacterStatus(90000001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Fatalf("Expected 3 calls, made %d", calls)
+	}
+	if status.SolarSystemID != 30000142 || status.StationID != 60003760 || !status.Online || status.Logins != 42 ||
+		status.LastLogin.Year() != 2020 || status.ShipTypeID != 587 || status.ShipName != "Rifter" {
+		t.Fatalf("Expected the three responses combined, got %+v", status)
+	}
+}
+
+func TestCharacterSheet(t *testing.T) {
+	var calls int32
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/characters/90000001/skills/"):
+			return stubResponse(200, `{"skills": [{"skill_id": 3300, "active_skill_level": 4, "trained_skill_level": 5, "skillpoints_in_skill": 256000}], "total_sp": 5000000, "unallocated_sp": 1000}`), nil
+		case strings.HasSuffix(req.URL.Path, "/characters/90000001/attributes/"):
+			return stubResponse(200, `{"charisma": 20, "intelligence": 24, "memory": 21, "perception": 17, "willpower": 17, "bonus_remaps": 2, "last_remap_date": "2019-06-01T00:00:00Z"}`), nil
+		case strings.HasSuffix(req.URL.Path, "/characters/90000001/clones/"):
+			return stubResponse(200, `{"home_location": {"location_id": 60003760, "location_type": "station"}, "jump_clones": [{"jump_clone_id": 12, "location_id": 1021975535893, "location_type": "structure", "implants": [9941, 9942]}], "last_clone_jump_date": "2020-01-02T03:04:05.5Z"}`), nil
+		}
+		t.Fatalf("Unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	sheet, err := e.CharacterSheet(90000001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Fatalf("Expected 3 calls, made %d", calls)
+	}
+	if len(sheet.Skills) != 1 || sheet.Skills[0].ActiveLevel != 4 || sheet.Skills[0].TrainedLevel != 5 || sheet.TotalSP != 5000000 || sheet.UnallocatedSP != 1000 {
+		t.Fatalf("Unexpected skills: %+v", sheet)
+	}
+	if sheet.Attributes.Intelligence != 24 || sheet.Attributes.BonusRemaps != 2 || sheet.Attributes.LastRemapDate.Year() != 2019 {
+		t.Fatalf("Unexpected attributes: %+v", sheet.Attributes)
+	}
+	clones := sheet.Clones
+	if clones.HomeLocation.LocationID != 60003760 || len(clones.JumpClones) != 1 || len(clones.JumpClones[0].Implants) != 2 ||
+		clones.LastCloneJumpDate.Day() != 2 || !clones.LastStationChangeDate.IsZero() {
+		t.Fatalf("Unexpected clones: %+v", clones)
+	}
+}
+
+func TestCharacterStatusError(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/online/") {
+			return stubResponse(403, `{"error": "Token is not valid for scope(s)"}`), nil
+		}
+		return stubResponse(200, `{}`), nil
+	})
+	if status, err := e.CharacterStatus(90000001); err == nil || status != nil {
+		t.Fatalf("Expected the failed call's error, got %+v, %v", status, err)
+	}
+}
+
+func TestCharacterPortraits(t *testing.T) {
+	var calls int32
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		if !strings.HasSuffix(req.URL.Path, "/characters/90000001/portrait/") || req.Header.Get("Authorization") != "" {
+			t.Fatalf("Unexpected request to %s with headers %v", req.URL, req.Header)
+		}
+		return stubResponse(200, `{"px64x64": "https://images.evetech.net/characters/90000001/portrait?size=64", "px128x128": "https://images.evetech.net/characters/90000001/portrait?size=128", "px256x256": "https://images.evetech.net/characters/90000001/portrait?size=256", "px512x512": "https://images.evetech.net/characters/90000001/portrait?size=512"}`,
+			"Expires", time.Now().UTC().Add(time.Hour).Format(http.TimeFormat)), nil
+	})
+	e.AccessToken = "token"
+	for i := 0; i < 2; i++ {
+		portraits, err := e.CharacterPortraits(90000001)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(portraits.Px64, "size=64") || !strings.HasSuffix(portraits.Px512, "size=512") || portraits.Px128 == "" || portraits.Px256 == "" {
+			t.Fatalf("Unexpected portraits: %+v", portraits)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("Expected the portraits to be cached, made %d calls", calls)
+	}
+}
+
+func TestWarmCharacter(t *testing.T) {
+	var mu sync.Mutex
+	requested := make(map[string]bool)
+	expires := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		requested[strings.TrimPrefix(req.URL.Path, "/latest/")] = true
+		mu.Unlock()
+		return stubResponse(200, `{}`, "Expires", expires), nil
+	})
+	if err := e.WarmCharacter(90000001); err != nil {
+		t.Fatal(err)
+	}
+	if len(requested) != 3 || !requested["characters/90000001/portrait/"] || requested["characters/90000001/location/"] {
+		t.Fatalf("Expected only the public routes without a token, got %v", requested)
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub": "CHARACTER:EVE:90000001", "scp": ["esi-location.read_location.v1"]}`))
+	e.AccessToken = "e30." + payload + ".c2ln"
+	requested = make(map[string]bool)
+	if err := e.WarmCharacter(90000001); err != nil {
+		t.Fatal(err)
+	}
+	if len(requested) != 1 || !requested["characters/90000001/location/"] {
+		t.Fatalf("Expected only the newly granted route to be fetched, got %v", requested)
+	}
+}
@@ -0,0 +1,40 @@
+package goesi
+
+import (
+	"github.com/Jeffail/gabs"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ESIClient is the set of methods for authenticating with the SSO and calling ESI.
+// *ESI implements it; depend on this interface instead of the struct to be able
+// to swap in a mock of ESI in your own tests.
+//
+// The signatures of the methods here don't change, but methods may be added to the
+// interface as the library grows. Embed ESIClient in your mock struct, and implement
+// just the methods your code calls, so that it keeps compiling when one is added.
+type ESIClient interface {
+	GetAuthorizeURL() (string, error)
+	Authenticate(code string) error
+	RefreshAccessToken() error
+	WhoAmI() (*gabs.Container, error)
+	VerifyTokenSignature() (*TokenClaims, error)
+
+	Get(path string, args ...interface{}) (*gabs.Container, error)
+	GetWithParams(path string, params url.Values, args ...interface{}) (*gabs.Container, error)
+	GetPublic(path string, args ...interface{}) (*gabs.Container, error)
+	GetStaleOK(path string, args ...interface{}) (*gabs.Container, error)
+	GetIfNoneMatch(etag, path string, args ...interface{}) (*gabs.Container, string, bool, error)
+	GetWithVersionFallback(path string, versions []string, args ...interface{}) (*gabs.Container, error)
+	GetMany(paths []string) (map[string]*gabs.Container, error)
+	GetAllPages(path string, args ...interface{}) (*gabs.Container, error)
+	Post(path, data string) (*gabs.Container, error)
+	PostJSON(path string, v interface{}, opts ...RequestOption) (*gabs.Container, error)
+	Put(path, data string) (*gabs.Container, error)
+	PutJSON(path string, v interface{}) (*gabs.Container, error)
+	Delete(path string) (*gabs.Container, error)
+	Do(method, path string, body io.Reader, args ...interface{}) (*http.Response, error)
+}
+
+var _ ESIClient = (*ESI)(nil)
@@ -0,0 +1,63 @@
+package goesi
+
+import (
+	"context"
+	"time"
+)
+
+// A Clock tells the current time and waits. Everything in the package that needs the
+// current time, like cache and token expiry checks, reads it from the ESI struct's clock,
+// and everything that waits, like retries and polling, waits on it, so that tests can
+// control time instead of sleeping.
+type Clock interface {
+	Now() time.Time
+	// After returns a channel that receives the time once the duration has passed
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the Clock that tells the actual time
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// now returns the current time from the clock, or the actual time if there is no clock
+func now(c Clock) time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
+
+// SetClock sets the clock used for all time checks. Set it before making any calls.
+func (e *ESI) SetClock(c Clock) {
+	e.clock = c
+	e.cache.clock = c
+}
+
+// now returns the current time from the ESI struct's clock
+func (e *ESI) now() time.Time {
+	return now(e.clock)
+}
+
+// sleep waits on the ESI struct's clock for the duration, returning early with the
+// context's error if it's cancelled
+func (e *ESI) sleep(ctx context.Context, d time.Duration) error {
+	var after <-chan time.Time
+	if e.clock == nil {
+		after = time.After(d)
+	} else {
+		after = e.clock.After(d)
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-after:
+		return nil
+	}
+}
@@ -0,0 +1,123 @@
+package goesi
+
+import (
+	"github.com/Jeffail/gabs"
+	"net/http"
+	"sync"
+)
+
+// defaultMaxConcurrency is how many calls are made at the same time by methods that make many calls
+const defaultMaxConcurrency = 20
+
+// GetMany fetches each of the paths concurrently, like calling Get for each.
+// At most MaxConcurrency calls are made at the same time. The responses are returned
+// keyed by path; if any of the calls fail, the successful responses are still returned,
+// along with a *MultiError holding the error for each path that failed.
+func (e *ESI) GetMany(paths []string) (map[string]*gabs.Container, error) {
+	return e.GetManyWithOptions(paths)
+}
+
+// GetManyWithOptions is like GetMany, with the options applied to every call. With
+// WithDeadline, or a context with a deadline, the calls all share the one deadline: once
+// it passes, no more calls are made and the paths that weren't fetched fail with
+// context.DeadlineExceeded.
+func (e *ESI) GetManyWithOptions(paths []string, opts ...RequestOption) (map[string]*gabs.Container, error) {
+	options, cancel := newRequestOptions(opts).withDeadline()
+	defer cancel()
+	responses, errs := e.getEach(paths, options)
+	results := make(map[string]*gabs.Container, len(paths))
+	failures := make(map[string]error)
+	for i, path := range paths {
+		if errs[i] != nil {
+			failures[path] = errs[i]
+			continue
+		}
+		results[path] = responses[i]
+	}
+	if len(failures) > 0 {
+		return results, &MultiError{Errors: failures, Total: len(paths)}
+	}
+	return results, nil
+}
+
+// getAll fetches each of the paths concurrently through Get, returning the responses
+// in the same order as the paths. If any of the calls fail, the first error is returned.
+func (e *ESI) getAll(paths []string) ([]*gabs.Container, error) {
+	results, errs := e.getEach(paths, requestOptions{})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// getEach fetches each of the paths concurrently through Get, returning the responses and
+// errors in the same order as the paths.
+func (e *ESI) getEach(paths []string, opts requestOptions) ([]*gabs.Container, []error) {
+	urls := make([]string, len(paths))
+	for i, path := range paths {
+		urls[i] = e.buildURL(path)
+	}
+	results, _, errs := e.getURLs(urls, opts)
+	return results, errs
+}
+
+// getURLs fetches each of the URLs concurrently, returning the responses, their headers,
+// and errors in the same order as the URLs. At most MaxConcurrency calls are made at the same time.
+// Once the options' context is done, the calls that haven't started fail with its error.
+func (e *ESI) getURLs(urls []string, opts requestOptions) ([]*gabs.Container, []http.Header, []error) {
+	results := make([]*gabs.Container, len(urls))
+	headers := make([]http.Header, len(urls))
+	errs := make([]error, len(urls))
+	limit := e.MaxConcurrency
+	if limit <= 0 {
+		limit = defaultMaxConcurrency
+	}
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if opts.ctx != nil && opts.ctx.Err() != nil {
+				errs[i] = opts.ctx.Err()
+				return
+			}
+			results[i], headers[i], errs[i] = e.getWith(u, opts)
+		}(i, u)
+	}
+	wg.Wait()
+	return results, headers, errs
+}
+
+// inflight tracks the URLs that have a background refresh running
+type inflight struct {
+	mu   sync.Mutex
+	urls map[string]bool
+}
+
+// newInflight creates an empty set of in-flight URLs
+func newInflight() *inflight {
+	return &inflight{urls: make(map[string]bool)}
+}
+
+// start marks the URL as in flight, returning false if it already was
+func (f *inflight) start(u string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.urls[u] {
+		return false
+	}
+	f.urls[u] = true
+	return true
+}
+
+// done marks the URL as no longer in flight
+func (f *inflight) done(u string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.urls, u)
+}
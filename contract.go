@@ -0,0 +1,108 @@
+package goesi
+
+import (
+	"time"
+)
+
+// Contract types
+const (
+	ContractUnknown      = "unknown"
+	ContractItemExchange = "item_exchange"
+	ContractAuction      = "auction"
+	ContractCourier      = "courier"
+	ContractLoan         = "loan"
+)
+
+// Contract statuses
+const (
+	ContractOutstanding        = "outstanding"
+	ContractInProgress         = "in_progress"
+	ContractFinishedIssuer     = "finished_issuer"
+	ContractFinishedContractor = "finished_contractor"
+	ContractFinished           = "finished"
+	ContractCancelled          = "cancelled"
+	ContractRejected           = "rejected"
+	ContractFailed             = "failed"
+	ContractDeleted            = "deleted"
+	ContractReversed           = "reversed"
+)
+
+// Contract is a contract a character issued or was offered. Type and Status are one of
+// the Contract* consts. Dates that haven't happened yet, like DateAccepted for an
+// outstanding contract, are the zero time.
+type Contract struct {
+	ContractID          int32     `json:"contract_id"`
+	Type                string    `json:"type"`
+	Status              string    `json:"status"`
+	Title               string    `json:"title"`
+	Availability        string    `json:"availability"`
+	ForCorporation      bool      `json:"for_corporation"`
+	IssuerID            int32     `json:"issuer_id"`
+	IssuerCorporationID int32     `json:"issuer_corporation_id"`
+	AssigneeID          int32     `json:"assignee_id"`
+	AcceptorID          int32     `json:"acceptor_id"`
+	StartLocationID     int64     `json:"start_location_id"`
+	EndLocationID       int64     `json:"end_location_id"`
+	Price               float64   `json:"price"`
+	Reward              float64   `json:"reward"`
+	Collateral          float64   `json:"collateral"`
+	Buyout              float64   `json:"buyout"`
+	Volume              float64   `json:"volume"`
+	DaysToComplete      int32     `json:"days_to_complete"`
+	DateIssued          time.Time `json:"date_issued"`
+	DateExpired         time.Time `json:"date_expired"`
+	DateAccepted        time.Time `json:"date_accepted"`
+	DateCompleted       time.Time `json:"date_completed"`
+}
+
+// UnmarshalJSON decodes the contract, parsing its dates with ParseESITime
+func (c *Contract) UnmarshalJSON(data []byte) error {
+	type contract Contract
+	return decodeWithTimes(data, (*contract)(c), map[string]*time.Time{
+		"date_issued":    &c.DateIssued,
+		"date_expired":   &c.DateExpired,
+		"date_accepted":  &c.DateAccepted,
+		"date_completed": &c.DateCompleted,
+	})
+}
+
+// ContractItem is an item in an item exchange, auction, or loan contract. Items that
+// aren't included are the ones the issuer asks for in exchange.
+type ContractItem struct {
+	RecordID    int64 `json:"record_id"`
+	TypeID      int32 `json:"type_id"`
+	Quantity    int32 `json:"quantity"`
+	RawQuantity int32 `json:"raw_quantity"`
+	IsSingleton bool  `json:"is_singleton"`
+	IsIncluded  bool  `json:"is_included"`
+}
+
+// CharacterContracts fetches all pages of the character's contracts. The access token
+// needs the esi-contracts.read_character_contracts.v1 scope.
+func (e *ESI) CharacterContracts(characterID int32) ([]Contract, error) {
+	data, err := e.GetAllPages("characters/%d/contracts", characterID)
+	if err != nil {
+		return nil, err
+	}
+	var contracts []Contract
+	if err := decode(data, &contracts); err != nil {
+		e.log.Error("Error parsing contracts response", "characterID", characterID, "error", err)
+		return nil, err
+	}
+	return contracts, nil
+}
+
+// ContractItems fetches the items in one of the character's contracts. Courier contracts
+// have no items. The access token needs the esi-contracts.read_character_contracts.v1 scope.
+func (e *ESI) ContractItems(characterID int32, contractID int32) ([]ContractItem, error) {
+	data, err := e.Get("characters/%d/contracts/%d/items", characterID, contractID)
+	if err != nil {
+		return nil, err
+	}
+	var items []ContractItem
+	if err := decode(data, &items); err != nil {
+		e.log.Error("Error parsing contract items response", "characterID", characterID, "contractID", contractID, "error", err)
+		return nil, err
+	}
+	return items, nil
+}
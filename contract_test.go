@@ -0,0 +1,55 @@
+package goesi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCharacterContracts(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(req.URL.Path, "/characters/90000001/contracts/") {
+			t.Fatalf("Unexpected request to %s", req.URL)
+		}
+		switch req.URL.Query().Get("page") {
+		case "1":
+			return stubResponse(200, `[{"contract_id": 1, "type": "item_exchange", "status": "outstanding", "issuer_id": 90000001, "price": 1500000.5, "date_issued": "2020-01-02T03:04:05Z", "date_expired": "2020-01-16T03:04:05Z"}]`, PagesHeader, "2"), nil
+		case "2":
+			return stubResponse(200, `[{"contract_id": 2, "type": "courier", "status": "finished", "reward": 10000000, "collateral": 500000000, "date_issued": "2020-01-03T00:00:00Z", "date_accepted": "2020-01-03T01:00:00.5Z", "date_completed": "2020-01-04T00:00:00Z"}]`, PagesHeader, "2"), nil
+		}
+		t.Fatalf("Unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	contracts, err := e.CharacterContracts(90000001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contracts) != 2 {
+		t.Fatalf("Expected the contracts from both pages, got %+v", contracts)
+	}
+	first, second := contracts[0], contracts[1]
+	if first.Type != ContractItemExchange || first.Status != ContractOutstanding || first.Price != 1500000.5 ||
+		first.DateIssued.Day() != 2 || !first.DateAccepted.IsZero() {
+		t.Fatalf("Unexpected first contract: %+v", first)
+	}
+	if second.Type != ContractCourier || second.Status != ContractFinished || second.Collateral != 500000000 ||
+		second.DateAccepted.Hour() != 1 || second.DateCompleted.Day() != 4 {
+		t.Fatalf("Unexpected second contract: %+v", second)
+	}
+}
+
+func TestContractItems(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(req.URL.Path, "/characters/90000001/contracts/1/items/") {
+			t.Fatalf("Unexpected request to %s", req.URL)
+		}
+		return stubResponse(200, `[{"record_id": 10, "type_id": 34, "quantity": 1000, "is_included": true}, {"record_id": 11, "type_id": 587, "quantity": 1, "is_singleton": true, "is_included": false}]`), nil
+	})
+	items, err := e.ContractItems(90000001, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].TypeID != 34 || items[0].Quantity != 1000 || !items[0].IsIncluded || items[1].IsIncluded || !items[1].IsSingleton {
+		t.Fatalf("Unexpected items: %+v", items)
+	}
+}
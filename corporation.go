@@ -0,0 +1,134 @@
+package goesi
+
+import (
+	"time"
+)
+
+// CorporationInfo is the public information about a corporation
+type CorporationInfo struct {
+	CorporationID int32     `json:"-"`
+	Name          string    `json:"name"`
+	Ticker        string    `json:"ticker"`
+	MemberCount   int32     `json:"member_count"`
+	CEOID         int32     `json:"ceo_id"`
+	CreatorID     int32     `json:"creator_id"`
+	AllianceID    int32     `json:"alliance_id"`
+	FactionID     int32     `json:"faction_id"`
+	DateFounded   time.Time `json:"date_founded"`
+	Description   string    `json:"description"`
+	HomeStationID int32     `json:"home_station_id"`
+	Shares        int64     `json:"shares"`
+	TaxRate       float64   `json:"tax_rate"`
+	URL           string    `json:"url"`
+	WarEligible   bool      `json:"war_eligible"`
+}
+
+// UnmarshalJSON decodes the corporation, parsing its founding date with ParseESITime
+func (c *CorporationInfo) UnmarshalJSON(data []byte) error {
+	type corporationInfo CorporationInfo
+	return decodeWithTimes(data, (*corporationInfo)(c), map[string]*time.Time{
+		"date_founded": &c.DateFounded,
+	})
+}
+
+// Corporation fetches the public information about the corporation
+func (e *ESI) Corporation(id int32) (*CorporationInfo, error) {
+	data, err := e.Get("corporations/%d", id)
+	if err != nil {
+		return nil, err
+	}
+	var info CorporationInfo
+	if err := decode(data, &info); err != nil {
+		e.log.Error("Error parsing corporation response", "corporationID", id, "error", err)
+		return nil, err
+	}
+	info.CorporationID = id
+	return &info, nil
+}
+
+// StructureService is a service module fitted to a structure, and whether it's online
+type StructureService struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// Structure is an Upwell structure owned by a corporation. FuelExpires is the zero time
+// for structures without fuel, and the state timers are the zero time when there isn't one.
+type Structure struct {
+	StructureID     int64              `json:"structure_id"`
+	TypeID          int32              `json:"type_id"`
+	CorporationID   int32              `json:"corporation_id"`
+	SystemID        int32              `json:"system_id"`
+	ProfileID       int32              `json:"profile_id"`
+	Name            string             `json:"name"`
+	State           string             `json:"state"`
+	ReinforceHour   int32              `json:"reinforce_hour"`
+	Services        []StructureService `json:"services"`
+	FuelExpires     time.Time          `json:"fuel_expires"`
+	StateTimerStart time.Time          `json:"state_timer_start"`
+	StateTimerEnd   time.Time          `json:"state_timer_end"`
+	UnanchorsAt     time.Time          `json:"unanchors_at"`
+}
+
+// UnmarshalJSON decodes the structure, parsing its times with ParseESITime
+func (s *Structure) UnmarshalJSON(data []byte) error {
+	type structure Structure
+	return decodeWithTimes(data, (*structure)(s), map[string]*time.Time{
+		"fuel_expires":      &s.FuelExpires,
+		"state_timer_start": &s.StateTimerStart,
+		"state_timer_end":   &s.StateTimerEnd,
+		"unanchors_at":      &s.UnanchorsAt,
+	})
+}
+
+// Starbase is a control tower (POS) owned by a corporation
+type Starbase struct {
+	StarbaseID      int64     `json:"starbase_id"`
+	TypeID          int32     `json:"type_id"`
+	SystemID        int32     `json:"system_id"`
+	MoonID          int32     `json:"moon_id"`
+	State           string    `json:"state"`
+	OnlinedSince    time.Time `json:"onlined_since"`
+	ReinforcedUntil time.Time `json:"reinforced_until"`
+	UnanchorAt      time.Time `json:"unanchor_at"`
+}
+
+// UnmarshalJSON decodes the starbase, parsing its times with ParseESITime
+func (s *Starbase) UnmarshalJSON(data []byte) error {
+	type starbase Starbase
+	return decodeWithTimes(data, (*starbase)(s), map[string]*time.Time{
+		"onlined_since":    &s.OnlinedSince,
+		"reinforced_until": &s.ReinforcedUntil,
+		"unanchor_at":      &s.UnanchorAt,
+	})
+}
+
+// CorporationStructures fetches all pages of the corporation's structures. The access token
+// needs the esi-corporations.read_structures.v1 scope, and its character the Station_Manager role.
+func (e *ESI) CorporationStructures(corpID int32) ([]Structure, error) {
+	data, err := e.GetAllPages("corporations/%d/structures", corpID)
+	if err != nil {
+		return nil, err
+	}
+	var structures []Structure
+	if err := decode(data, &structures); err != nil {
+		e.log.Error("Error parsing structures response", "corporationID", corpID, "error", err)
+		return nil, err
+	}
+	return structures, nil
+}
+
+// CorporationStarbases fetches all pages of the corporation's starbases. The access token
+// needs the esi-corporations.read_starbases.v1 scope, and its character the Director role.
+func (e *ESI) CorporationStarbases(corpID int32) ([]Starbase, error) {
+	data, err := e.GetAllPages("corporations/%d/starbases", corpID)
+	if err != nil {
+		return nil, err
+	}
+	var starbases []Starbase
+	if err := decode(data, &starbases); err != nil {
+		e.log.Error("Error parsing starbases response", "corporationID", corpID, "error", err)
+		return nil, err
+	}
+	return starbases, nil
+}
@@ -0,0 +1,79 @@
+package goesi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCorporation(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(req.URL.Path, "/corporations/98000001/") {
+			t.Fatalf("Unexpected request to %s", req.URL)
+		}
+		return stubResponse(200, `{"name": "Goesi Holdings", "ticker": "GOESI", "member_count": 12, "ceo_id": 90000001, "alliance_id": 99000001, "date_founded": "2017-11-09T17:00:00Z", "tax_rate": 0.1}`), nil
+	})
+	info, err := e.Corporation(98000001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.CorporationID != 98000001 || info.Name != "Goesi Holdings" || info.Ticker != "GOESI" || info.MemberCount != 12 ||
+		info.AllianceID != 99000001 || info.DateFounded.Year() != 2017 || info.TaxRate != 0.1 {
+		t.Fatalf("Unexpected corporation: %+v", info)
+	}
+}
+
+func TestCorporationNotFound(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		return stubResponse(404, `{"error": "Corporation not found"}`), nil
+	})
+	if info, err := e.Corporation(1); err == nil || info != nil {
+		t.Fatalf("Expected an error, got %+v, %v", info, err)
+	}
+}
+
+func TestCorporationStructures(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(req.URL.Path, "/corporations/98000001/structures/") {
+			t.Fatalf("Unexpected request to %s", req.URL)
+		}
+		switch req.URL.Query().Get("page") {
+		case "1":
+			return stubResponse(200, `[{"structure_id": 1021975535893, "type_id": 35832, "system_id": 30000142, "state": "shield_vulnerable", "fuel_expires": "2020-01-10T00:00:00Z", "services": [{"name": "Clone Bay", "state": "online"}]}]`, PagesHeader, "2"), nil
+		case "2":
+			return stubResponse(200, `[{"structure_id": 1021975535894, "type_id": 35833, "state": "anchoring", "state_timer_end": "2020-01-02T03:04:05.5Z"}]`, PagesHeader, "2"), nil
+		}
+		t.Fatalf("Unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	structures, err := e.CorporationStructures(98000001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(structures) != 2 {
+		t.Fatalf("Expected the structures from both pages, got %+v", structures)
+	}
+	first, second := structures[0], structures[1]
+	if first.TypeID != 35832 || first.State != "shield_vulnerable" || first.FuelExpires.Day() != 10 || len(first.Services) != 1 || first.Services[0].Name != "Clone Bay" {
+		t.Fatalf("Unexpected first structure: %+v", first)
+	}
+	if second.State != "anchoring" || second.StateTimerEnd.Hour() != 3 || !second.FuelExpires.IsZero() {
+		t.Fatalf("Unexpected second structure: %+v", second)
+	}
+}
+
+func TestCorporationStarbases(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(req.URL.Path, "/corporations/98000001/starbases/") {
+			t.Fatalf("Unexpected request to %s", req.URL)
+		}
+		return stubResponse(200, `[{"starbase_id": 1000000000001, "type_id": 12235, "system_id": 30000142, "moon_id": 40009082, "state": "online", "onlined_since": "2019-06-01T00:00:00Z"}]`, PagesHeader, "1"), nil
+	})
+	starbases, err := e.CorporationStarbases(98000001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(starbases) != 1 || starbases[0].MoonID != 40009082 || starbases[0].State != "online" || starbases[0].OnlinedSince.Year() != 2019 || !starbases[0].ReinforcedUntil.IsZero() {
+		t.Fatalf("Unexpected starbases: %+v", starbases)
+	}
+}
@@ -0,0 +1,58 @@
+package goesi
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// redactedToken replaces the access token in commands from CurlFor
+const redactedToken = "<redacted>"
+
+// CurlFor returns a curl command that makes the same call to ESI as this struct would,
+// with the same headers, for reproducing problems in bug reports. The access token is
+// redacted; use CurlWithToken to include it. RequestOptions can be passed after the
+// format args, as with Get; their params and headers are included.
+func (e *ESI) CurlFor(method, path string, args ...interface{}) string {
+	return e.curl(method, path, args, true)
+}
+
+// CurlWithToken is like CurlFor, but includes the access token. Anyone with the
+// command can make calls as the character until the token expires, so don't share it.
+func (e *ESI) CurlWithToken(method, path string, args ...interface{}) string {
+	return e.curl(method, path, args, false)
+}
+
+// curl builds the curl command for the call, with the access token redacted if redact is set
+func (e *ESI) curl(method, path string, args []interface{}, redact bool) string {
+	method = strings.ToUpper(method)
+	url, opts := e.optionsURL(path, args)
+	// build the headers the same way as for a real call
+	req := &http.Request{Header: http.Header{}}
+	setupHeaders(e, req)
+	opts.setHeaders(req.Header)
+	header := req.Header
+	if redact && header.Get("Authorization") != "" {
+		header.Set("Authorization", "Bearer "+redactedToken)
+	}
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := []string{"curl"}
+	if method != "GET" {
+		parts = append(parts, "-X", method)
+	}
+	for _, key := range keys {
+		parts = append(parts, "-H", shellQuote(key+": "+header.Get(key)))
+	}
+	parts = append(parts, shellQuote(url))
+	return strings.Join(parts, " ")
+}
+
+// shellQuote quotes a string for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,21 @@
+package goesi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCurlFor(t *testing.T) {
+	e := New("clientID", "clientSecret", "http://localhost/callback")
+	e.UserAgent = "My App (contact: someone's@example.com)"
+	e.AccessToken = "secret-token"
+
+	expected := `curl -X POST -H 'Accept: application/json' -H 'Authorization: Bearer <redacted>' ` +
+		`-H 'User-Agent: My App (contact: someone'\''s@example.com)' 'https://esi.tech.ccp.is/latest/characters/90000001/mail/'`
+	if got := e.CurlFor("post", "characters/%d/mail", 90000001); got != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s", expected, got)
+	}
+	if got := e.CurlWithToken("GET", "characters/%d/wallet", 90000001); !strings.Contains(got, "Bearer secret-token") || strings.Contains(got, "-X") {
+		t.Fatalf("Expected the token in a GET command, got %s", got)
+	}
+}
@@ -0,0 +1,79 @@
+package goesi
+
+// DogmaAttribute is one of the dogma attributes of items, like a ship's shield capacity
+type DogmaAttribute struct {
+	AttributeID  int32   `json:"attribute_id"`
+	Name         string  `json:"name"`
+	DisplayName  string  `json:"display_name"`
+	Description  string  `json:"description"`
+	DefaultValue float64 `json:"default_value"`
+	IconID       int32   `json:"icon_id"`
+	UnitID       int32   `json:"unit_id"`
+	Published    bool    `json:"published"`
+	Stackable    bool    `json:"stackable"`
+	HighIsGood   bool    `json:"high_is_good"`
+}
+
+// DogmaModifier is how a dogma effect changes an attribute
+type DogmaModifier struct {
+	Func                 string `json:"func"`
+	Domain               string `json:"domain"`
+	ModifiedAttributeID  int32  `json:"modified_attribute_id"`
+	ModifyingAttributeID int32  `json:"modifying_attribute_id"`
+	Operator             int32  `json:"operator"`
+	EffectID             int32  `json:"effect_id"`
+}
+
+// DogmaEffect is one of the dogma effects of items, like a module's bonus to an attribute
+type DogmaEffect struct {
+	EffectID                 int32           `json:"effect_id"`
+	Name                     string          `json:"name"`
+	DisplayName              string          `json:"display_name"`
+	Description              string          `json:"description"`
+	EffectCategory           int32           `json:"effect_category"`
+	IconID                   int32           `json:"icon_id"`
+	PreExpression            int32           `json:"pre_expression"`
+	PostExpression           int32           `json:"post_expression"`
+	IsOffensive              bool            `json:"is_offensive"`
+	IsAssistance             bool            `json:"is_assistance"`
+	IsWarpSafe               bool            `json:"is_warp_safe"`
+	Published                bool            `json:"published"`
+	DisallowAutoRepeat       bool            `json:"disallow_auto_repeat"`
+	DurationAttributeID      int32           `json:"duration_attribute_id"`
+	DischargeAttributeID     int32           `json:"discharge_attribute_id"`
+	RangeAttributeID         int32           `json:"range_attribute_id"`
+	FalloffAttributeID       int32           `json:"falloff_attribute_id"`
+	TrackingSpeedAttributeID int32           `json:"tracking_speed_attribute_id"`
+	ElectronicChance         bool            `json:"electronic_chance"`
+	RangeChance              bool            `json:"range_chance"`
+	Modifiers                []DogmaModifier `json:"modifiers"`
+}
+
+// DogmaAttribute fetches the dogma attribute. Dogma only changes with game updates, so
+// it's cached for a day by default (see CacheTTLOverrides).
+func (e *ESI) DogmaAttribute(id int32) (*DogmaAttribute, error) {
+	data, err := e.GetPublic("dogma/attributes/%d", id)
+	if err != nil {
+		return nil, err
+	}
+	var attribute DogmaAttribute
+	if err := decode(data, &attribute); err != nil {
+		e.log.Error("Error parsing dogma attribute response", "attributeID", id, "error", err)
+		return nil, err
+	}
+	return &attribute, nil
+}
+
+// DogmaEffect fetches the dogma effect, cached for a day by default like DogmaAttribute
+func (e *ESI) DogmaEffect(id int32) (*DogmaEffect, error) {
+	data, err := e.GetPublic("dogma/effects/%d", id)
+	if err != nil {
+		return nil, err
+	}
+	var effect DogmaEffect
+	if err := decode(data, &effect); err != nil {
+		e.log.Error("Error parsing dogma effect response", "effectID", id, "error", err)
+		return nil, err
+	}
+	return &effect, nil
+}
@@ -0,0 +1,51 @@
+package goesi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDogma(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)}
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		expires := clock.now.Add(time.Hour).Format(http.TimeFormat)
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/dogma/attributes/263/"):
+			return stubResponse(200, `{"attribute_id": 263, "name": "shieldCapacity", "display_name": "Shield Capacity", "default_value": 0, "unit_id": 113, "published": true, "high_is_good": true}`, "Expires", expires), nil
+		case strings.HasSuffix(req.URL.Path, "/dogma/effects/4/"):
+			return stubResponse(200, `{"effect_id": 4, "name": "shieldBoosting", "effect_category": 1, "duration_attribute_id": 73, "modifiers": [{"func": "ItemModifier", "domain": "shipID", "modified_attribute_id": 264, "modifying_attribute_id": 68, "operator": 2}]}`, "Expires", expires), nil
+		}
+		t.Fatalf("Unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	e.SetClock(clock)
+	e.AccessToken = "token"
+
+	attribute, err := e.DogmaAttribute(263)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attribute.Name != "shieldCapacity" || attribute.UnitID != 113 || !attribute.HighIsGood {
+		t.Fatalf("Unexpected attribute: %+v", attribute)
+	}
+	effect, err := e.DogmaEffect(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if effect.Name != "shieldBoosting" || effect.DurationAttributeID != 73 || len(effect.Modifiers) != 1 || effect.Modifiers[0].ModifiedAttributeID != 264 {
+		t.Fatalf("Unexpected effect: %+v", effect)
+	}
+
+	// cached for a day, whatever the Expires header says
+	clock.now = clock.now.Add(12 * time.Hour)
+	if _, err := e.DogmaAttribute(263); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("Expected the attribute to still be cached, made %d calls", calls)
+	}
+}
@@ -0,0 +1,103 @@
+package goesi
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// ErrorLimitRemainHeader is the header with how many more calls can error before ESI starts responding with 420
+	ErrorLimitRemainHeader = "X-ESI-Error-Limit-Remain"
+	// ErrorLimitResetHeader is the header with the number of seconds until ESI's error limit resets
+	ErrorLimitResetHeader = "X-ESI-Error-Limit-Reset"
+	// errorLimitLow is the remaining error budget at which polling waits for the error limit to reset
+	errorLimitLow = 10
+)
+
+// errorLimit tracks ESI's error limit from the headers of its latest response
+type errorLimit struct {
+	mu     sync.Mutex
+	known  bool
+	remain int
+	reset  time.Time
+}
+
+// update records the error limit from a response's headers, if it has them
+func (l *errorLimit) update(h http.Header, now time.Time) {
+	if l == nil {
+		return
+	}
+	remain, err := strconv.Atoi(h.Get(ErrorLimitRemainHeader))
+	if err != nil {
+		return
+	}
+	seconds, err := strconv.Atoi(h.Get(ErrorLimitResetHeader))
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.known = true
+	l.remain = remain
+	l.reset = now.Add(time.Duration(seconds) * time.Second)
+}
+
+// wait returns how long to wait for the error limit to reset, if the remaining
+// error budget is low, or 0 if calls can carry on
+func (l *errorLimit) wait(now time.Time) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.known || l.remain > errorLimitLow || !l.reset.After(now) {
+		return 0
+	}
+	return l.reset.Sub(now)
+}
+
+// RateLimitState is where calls stand in ESI's error limit, as of the latest response
+type RateLimitState struct {
+	// Known is whether a response with the error limit headers has been seen yet
+	Known bool
+	// Remain is how many more calls can error before ESI starts responding with 420
+	Remain int
+	// Reset is when the error limit resets
+	Reset time.Time
+	// UntilReset is how long until the error limit resets, or 0 if it already has
+	UntilReset time.Duration
+}
+
+func (s RateLimitState) String() string {
+	if !s.Known {
+		return "error limit unknown (no responses with error limit headers yet)"
+	}
+	return fmt.Sprintf("%d errors remaining, resets in %s (at %s)",
+		s.Remain, s.UntilReset, s.Reset.UTC().Format(time.RFC3339))
+}
+
+// RateLimitStatus returns where calls stand in ESI's error limit, from the headers of
+// the latest response. Print it when debugging 420 responses.
+func (e *ESI) RateLimitStatus() RateLimitState {
+	return e.errorLimit.state(e.now())
+}
+
+// state returns the error limit as of now
+func (l *errorLimit) state(now time.Time) RateLimitState {
+	if l == nil {
+		return RateLimitState{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.known {
+		return RateLimitState{}
+	}
+	state := RateLimitState{Known: true, Remain: l.remain, Reset: l.reset}
+	if l.reset.After(now) {
+		state.UntilReset = l.reset.Sub(now)
+	}
+	return state
+}
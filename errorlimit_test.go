@@ -0,0 +1,37 @@
+package goesi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitStatus(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)}
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		return stubResponse(200, `{}`, ErrorLimitRemainHeader, "87", ErrorLimitResetHeader, "42"), nil
+	})
+	e.SetClock(clock)
+	if state := e.RateLimitStatus(); state.Known || !strings.Contains(state.String(), "unknown") {
+		t.Fatalf("Expected the error limit to be unknown before any calls, got %+v", state)
+	}
+
+	if _, err := e.Get("status"); err != nil {
+		t.Fatal(err)
+	}
+	clock.now = clock.now.Add(12 * time.Second)
+	state := e.RateLimitStatus()
+	if !state.Known || state.Remain != 87 || state.UntilReset != 30*time.Second {
+		t.Fatalf("Unexpected error limit state: %+v", state)
+	}
+	expected := "87 errors remaining, resets in 30s (at 2017-11-09T17:00:42Z)"
+	if state.String() != expected {
+		t.Fatalf("Expected '%s', got '%s'", expected, state)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	if state := e.RateLimitStatus(); state.UntilReset != 0 {
+		t.Fatalf("Expected the error limit to have reset, got %+v", state)
+	}
+}
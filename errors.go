@@ -0,0 +1,323 @@
+package goesi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RequestIDHeader is the header ESI tags each response with.
+// CCP asks for its value when reporting problems with ESI.
+const RequestIDHeader = "X-ESI-Request-ID"
+
+var (
+	// ErrMissingClientID is returned when the ESI struct has no ClientID set
+	ErrMissingClientID = errors.New("missing client ID")
+	// ErrMissingClientSecret is returned when the ESI struct has no ClientSecret set
+	ErrMissingClientSecret = errors.New("missing client secret")
+	// ErrMissingCallbackURL is returned when the ESI struct has no ClientCallbackURL set
+	ErrMissingCallbackURL = errors.New("missing client callback URL")
+	// ErrMissingRefreshToken is returned when refreshing the access token without a RefreshToken set
+	ErrMissingRefreshToken = errors.New("missing refresh token")
+	// ErrRefreshTokenInvalid is returned when the SSO rejects the refresh token.
+	// The token will not work again; the user has to authenticate again.
+	ErrRefreshTokenInvalid = errors.New("refresh token is no longer valid")
+	// ErrUnknownScope is returned when a scope is not one of AllScopes
+	ErrUnknownScope = errors.New("unknown scope")
+	// ErrNameNotFound is returned when resolving a name that doesn't match anything
+	ErrNameNotFound = errors.New("name not found")
+	// ErrAmbiguousName is returned when resolving a name that matches several things
+	ErrAmbiguousName = errors.New("name matches more than one ID")
+	// ErrUnsupportedCategory is returned when resolving a name in a category that has no details route
+	ErrUnsupportedCategory = errors.New("unsupported category")
+	// ErrStructureAccessDenied is returned when the character can't see a structure's
+	// information, as it doesn't have docking access to the structure
+	ErrStructureAccessDenied = errors.New("no access to structure")
+)
+
+// checkClientData returns an error for the first piece of client data that isn't set
+func checkClientData(e *ESI) error {
+	switch {
+	case e.ClientID == "":
+		return ErrMissingClientID
+	case e.ClientSecret == "":
+		return ErrMissingClientSecret
+	case e.ClientCallbackURL == "":
+		return ErrMissingCallbackURL
+	}
+	return nil
+}
+
+// An SSOError is returned when a call to the EVE SSO, like to authenticate, refresh the
+// access token, or verify it, fails. The SSO is a different service from ESI; check for
+// an *SSOError to tell the login service being down from ESI's data routes failing, which
+// return an *ESIError. A call that couldn't be made at all has a StatusCode of 0.
+type SSOError struct {
+	StatusCode int
+	// Code is the error code from the response body, like "invalid_grant"
+	Code string
+	// Description is the error description from the response body
+	Description string
+	URL         string
+	// Err is the cause of the error, if there is one, like ErrRefreshTokenInvalid
+	// or the HTTP client's error for a call that couldn't be made
+	Err error
+}
+
+func (e *SSOError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("SSO call to URL '%s' failed: %s", e.URL, e.Err)
+	}
+	msg := fmt.Sprintf("SSO responded with status code %d for URL '%s'", e.StatusCode, e.URL)
+	if e.Code != "" {
+		msg += ": " + e.Code
+	}
+	if e.Description != "" {
+		msg += " " + e.Description
+	}
+	return msg
+}
+
+// Unwrap returns the cause of the error
+func (e *SSOError) Unwrap() error {
+	return e.Err
+}
+
+// newSSOError returns the *SSOError for an SSO response with a status code other than 2xx,
+// with the error and description from the body if it has them
+func newSSOError(url string, resp *http.Response, body []byte) *SSOError {
+	var ssoErr ssoErrorResponse
+	json.Unmarshal(body, &ssoErr)
+	return &SSOError{
+		StatusCode:  resp.StatusCode,
+		Code:        ssoErr.Error,
+		Description: ssoErr.ErrorDescription,
+		URL:         url,
+	}
+}
+
+// An ESIError is returned when ESI responds with a status code other than 2xx
+type ESIError struct {
+	StatusCode int
+	// Message is the error message from the response body, if there was one
+	Message string
+	URL     string
+	// RequestID is the value of the response's X-ESI-Request-ID header, for reporting problems to CCP
+	RequestID string
+	// RetryAfter is how long ESI asked to wait before trying again, for rate limited responses
+	RetryAfter time.Duration
+	// ContentType is the response's Content-Type header. It isn't JSON when the response
+	// came from something in front of ESI, like an HTML error page from a proxy.
+	ContentType string
+}
+
+func (e *ESIError) Error() string {
+	msg := fmt.Sprintf("ESI responded with status code %d for URL '%s'", e.StatusCode, e.URL)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += " (request ID " + e.RequestID + ")"
+	}
+	if !isJSONContentType(e.ContentType) {
+		msg += fmt.Sprintf(" (content type '%s')", e.ContentType)
+	}
+	return msg
+}
+
+// Unwrap returns a *ContentTypeError if the response wasn't JSON, so that errors.As
+// finds one for error pages from in front of ESI, as it does for successful responses
+func (e *ESIError) Unwrap() error {
+	if isJSONContentType(e.ContentType) {
+		return nil
+	}
+	return &ContentTypeError{e.StatusCode, e.ContentType, e.URL}
+}
+
+// newESIError creates an ESIError from a response and its body
+func newESIError(url string, resp *http.Response, body []byte) *ESIError {
+	var parsed struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(body, &parsed)
+	esiErr := &ESIError{
+		StatusCode:  resp.StatusCode,
+		Message:     parsed.Error,
+		URL:         url,
+		RequestID:   resp.Header.Get(RequestIDHeader),
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if isRateLimitStatus(resp.StatusCode) {
+		esiErr.RetryAfter = retryAfter(resp.Header)
+	}
+	return esiErr
+}
+
+// A ContentTypeError is returned when a successful response is not JSON. Responses
+// with other status codes return an *ESIError, which wraps a ContentTypeError when
+// the response isn't JSON, like an HTML error page from a proxy in front of ESI.
+type ContentTypeError struct {
+	StatusCode  int
+	ContentType string
+	URL         string
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("expected JSON from URL '%s', got content type '%s' with status code %d", e.URL, e.ContentType, e.StatusCode)
+}
+
+// A ResponseTooLargeError is returned when a response body is longer than ESI.MaxResponseBytes.
+// The rest of the body isn't read, so nothing is cached for the call.
+type ResponseTooLargeError struct {
+	StatusCode int
+	Limit      int64
+	URL        string
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response from URL '%s' with status code %d is larger than the limit of %d bytes", e.URL, e.StatusCode, e.Limit)
+}
+
+// A StaleError is returned by Get, along with the expired cached data, when ESI.ServeStaleOnError
+// is set and the call fails. The data is still usable, but was due to be replaced at Expires.
+type StaleError struct {
+	URL     string
+	Expires time.Time
+	// Err is why the call failed
+	Err error
+}
+
+func (e *StaleError) Error() string {
+	return fmt.Sprintf("serving data for URL '%s' that expired at %s: %s", e.URL, e.Expires.Format(time.RFC3339), e.Err)
+}
+
+// Unwrap returns why the call failed
+func (e *StaleError) Unwrap() error {
+	return e.Err
+}
+
+// A SequenceError is returned by Sequence when one of its steps fails. The steps before
+// Step were made, and those after it weren't.
+type SequenceError struct {
+	// Step is the index of the failed operation
+	Step      int
+	Operation Operation
+	Err       error
+}
+
+func (e *SequenceError) Error() string {
+	return fmt.Sprintf("step %d (%s %s) failed: %s", e.Step, e.Operation.Method, e.Operation.Path, e.Err)
+}
+
+// Unwrap returns the error from the failed step, so that errors.As finds its *ESIError
+func (e *SequenceError) Unwrap() error {
+	return e.Err
+}
+
+// parseErrorSnippetLength is how much of the body is included in a ParseError
+const parseErrorSnippetLength = 200
+
+// A ParseError is returned when a response body can't be parsed as JSON.
+// It includes the start of the body, which usually shows what was sent
+// instead, like an HTML error page from a proxy.
+type ParseError struct {
+	StatusCode  int
+	ContentType string
+	// Snippet is the start of the response body
+	Snippet string
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("cannot parse response (status code %d, content type '%s') as JSON: %s; body starts with: %q",
+		e.StatusCode, e.ContentType, e.Err, e.Snippet)
+}
+
+// Unwrap returns the underlying parsing error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// newParseError creates a ParseError for a response and its body
+func newParseError(resp *http.Response, body []byte, err error) *ParseError {
+	snippet := body
+	if len(snippet) > parseErrorSnippetLength {
+		snippet = snippet[:parseErrorSnippetLength]
+	}
+	return &ParseError{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Snippet:     string(snippet),
+		Err:         err,
+	}
+}
+
+// A MultiError is returned by methods that make many calls when some of them fail.
+// It holds the error for each path that failed.
+type MultiError struct {
+	Errors map[string]error
+	// Total is the number of calls that were made
+	Total int
+}
+
+func (e *MultiError) Error() string {
+	paths := make([]string, 0, len(e.Errors))
+	for path := range e.Errors {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	parts := make([]string, len(paths))
+	for i, path := range paths {
+		parts[i] = fmt.Sprintf("'%s': %s", path, e.Errors[path])
+	}
+	return fmt.Sprintf("%d of %d calls failed: %s", len(e.Errors), e.Total, strings.Join(parts, "; "))
+}
+
+// Unwrap returns the individual errors, so that errors.Is and errors.As check each of them
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// A PageError is returned by GetAllPages when some of the pages after the first fail.
+// It holds the error for each page that failed.
+type PageError struct {
+	Errors map[int]error
+	// Pages is the number of pages the route has
+	Pages int
+}
+
+// FailedPages returns the numbers of the pages that failed, in order
+func (e *PageError) FailedPages() []int {
+	pages := make([]int, 0, len(e.Errors))
+	for page := range e.Errors {
+		pages = append(pages, page)
+	}
+	sort.Ints(pages)
+	return pages
+}
+
+func (e *PageError) Error() string {
+	pages := e.FailedPages()
+	parts := make([]string, len(pages))
+	for i, page := range pages {
+		parts[i] = fmt.Sprintf("page %d: %s", page, e.Errors[page])
+	}
+	return fmt.Sprintf("%d of %d pages failed: %s", len(pages), e.Pages, strings.Join(parts, "; "))
+}
+
+// Unwrap returns the individual errors, so that errors.Is and errors.As check each of them
+func (e *PageError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
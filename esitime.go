@@ -0,0 +1,62 @@
+package goesi
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// esiTimeFormats are the layouts ESI timestamps come in, tried in order. Timestamps
+// without a time zone are in UTC, like all of ESI's times.
+var esiTimeFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// ParseESITime parses a timestamp from an ESI response, like "2017-11-09T17:00:00Z".
+// It accepts RFC 3339 timestamps with or without fractional seconds, as well as
+// timestamps missing their time zone and plain dates, which are taken to be in UTC.
+func ParseESITime(s string) (time.Time, error) {
+	for _, format := range esiTimeFormats {
+		if t, err := time.Parse(format, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Cannot parse ESI time '%s'", s)
+}
+
+// decodeWithTimes decodes the JSON object into v, parsing the fields in times with
+// ParseESITime instead of into v. It's for the UnmarshalJSON methods of the typed results,
+// which pass a pointer to the result as a type without the method, so that it isn't
+// called again. Time fields that are missing or null are left as they are.
+func decodeWithTimes(data []byte, v interface{}, times map[string]*time.Time) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for key, t := range times {
+		raw, ok := fields[key]
+		if !ok {
+			continue
+		}
+		delete(fields, key)
+		var s *string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return fmt.Errorf("Cannot parse ESI time in '%s': %s", key, err)
+		}
+		if s == nil {
+			continue
+		}
+		parsed, err := ParseESITime(*s)
+		if err != nil {
+			return err
+		}
+		*t = parsed
+	}
+	rest, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(rest, v)
+}
@@ -0,0 +1,63 @@
+package goesi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseESITime(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected time.Time
+	}{
+		{"2017-11-09T17:00:00Z", time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)},
+		{"2017-11-09T17:00:00.25Z", time.Date(2017, time.November, 9, 17, 0, 0, 250000000, time.UTC)},
+		{"2017-11-09T19:00:00+02:00", time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)},
+		{"2017-11-09T17:00:00", time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)},
+		{"2017-11-09", time.Date(2017, time.November, 9, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		got, err := ParseESITime(test.s)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", test.s, err)
+		}
+		if !got.Equal(test.expected) || got.Location() != time.UTC {
+			t.Fatalf("%s: expected %s, got %s", test.s, test.expected, got)
+		}
+	}
+	for _, s := range []string{"", "yesterday", "09/11/2017"} {
+		if _, err := ParseESITime(s); err == nil {
+			t.Fatalf("Expected an error for '%s'", s)
+		}
+	}
+}
+
+func TestTypedResultTimes(t *testing.T) {
+	var contract Contract
+	err := json.Unmarshal([]byte(`{"contract_id": 1, "title": "2017-11-09", "date_issued": "2017-11-09T17:00:00.5Z", "date_expired": "2017-11-23T17:00:00", "date_accepted": null}`), &contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contract.ContractID != 1 || contract.Title != "2017-11-09" {
+		t.Fatalf("Expected the other fields to be decoded as usual, got %+v", contract)
+	}
+	if contract.DateIssued.Nanosecond() != 500000000 || contract.DateExpired.Day() != 23 || !contract.DateAccepted.IsZero() {
+		t.Fatalf("Unexpected dates: %+v", contract)
+	}
+
+	var status CharacterStatus
+	if err := json.Unmarshal([]byte(`{"last_login": "2017-11-09T17:00:00Z"}`), &status); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"online": true}`), &status); err != nil {
+		t.Fatal(err)
+	}
+	if !status.Online || status.LastLogin.Hour() != 17 {
+		t.Fatalf("Expected responses decoded into the same struct to keep the earlier times, got %+v", status)
+	}
+
+	if err := json.Unmarshal([]byte(`{"date": "soon"}`), &JournalEntry{}); err == nil {
+		t.Fatal("Expected an error for a bad date")
+	}
+}
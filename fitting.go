@@ -0,0 +1,70 @@
+package goesi
+
+import (
+	"fmt"
+)
+
+// Fitting is a ship fitting saved by a character
+type Fitting struct {
+	// FittingID is 0 for a fitting that's not been saved yet
+	FittingID   int32         `json:"fitting_id,omitempty"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	ShipTypeID  int32         `json:"ship_type_id"`
+	Items       []FittingItem `json:"items"`
+}
+
+// FittingItem is a module, charge, drone, or other item in a fitting
+type FittingItem struct {
+	TypeID int32 `json:"type_id"`
+	// Flag is the slot the item is in, like "HiSlot0" or "DroneBay"
+	Flag     string `json:"flag"`
+	Quantity int32  `json:"quantity"`
+}
+
+// Fittings fetches the character's saved fittings. Needs the esi-fittings.read_fittings.v1 scope.
+func (e *ESI) Fittings(characterID int32) ([]Fitting, error) {
+	data, err := e.Get("characters/%d/fittings", characterID)
+	if err != nil {
+		return nil, err
+	}
+	var fittings []Fitting
+	if err := decode(data, &fittings); err != nil {
+		e.log.Error("Error parsing fittings response", "characterID", characterID, "error", err)
+		return nil, err
+	}
+	return fittings, nil
+}
+
+// CreateFitting saves a new fitting for the character and returns its ID. The fitting's
+// FittingID is ignored. Needs the esi-fittings.write_fittings.v1 scope.
+func (e *ESI) CreateFitting(characterID int32, f Fitting) (int32, error) {
+	f.FittingID = 0
+	data, err := e.PostJSON(fmt.Sprintf("characters/%d/fittings", characterID), f)
+	if err != nil {
+		return 0, err
+	}
+	var created struct {
+		FittingID int32 `json:"fitting_id"`
+	}
+	if err := decode(data, &created); err != nil {
+		e.log.Error("Error parsing create fitting response", "characterID", characterID, "error", err)
+		return 0, err
+	}
+	e.dropCachedFittings(characterID)
+	return created.FittingID, nil
+}
+
+// DeleteFitting deletes one of the character's fittings. Needs the esi-fittings.write_fittings.v1 scope.
+func (e *ESI) DeleteFitting(characterID, fittingID int32) error {
+	if _, err := e.Delete(fmt.Sprintf("characters/%d/fittings/%d", characterID, fittingID)); err != nil {
+		return err
+	}
+	e.dropCachedFittings(characterID)
+	return nil
+}
+
+// dropCachedFittings removes the character's cached fittings, which are stale after a change to them
+func (e *ESI) dropCachedFittings(characterID int32) {
+	e.cache.remove(e.cacheKey("GET", e.buildURL(fmt.Sprintf("characters/%d/fittings", characterID))))
+}
@@ -0,0 +1,58 @@
+package goesi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestFittings(t *testing.T) {
+	var requests []string
+	fittings := `[{"fitting_id": 1, "name": "Rifter", "description": "", "ship_type_id": 587, "items": [{"type_id": 2881, "flag": "HiSlot0", "quantity": 1}]}]`
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		var body []byte
+		if req.Body != nil {
+			body, _ = ioutil.ReadAll(req.Body)
+		}
+		requests = append(requests, req.Method+" "+req.URL.Path+" "+string(body))
+		switch req.Method + " " + req.URL.Path {
+		case "GET /latest/characters/90000001/fittings/":
+			return stubResponse(200, fittings), nil
+		case "POST /latest/characters/90000001/fittings/":
+			return stubResponse(201, `{"fitting_id": 2}`), nil
+		case "DELETE /latest/characters/90000001/fittings/1/":
+			return stubResponse(204, ""), nil
+		}
+		t.Fatalf("Unexpected request %s %s", req.Method, req.URL)
+		return nil, nil
+	})
+	saved, err := e.Fittings(90000001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 1 || saved[0].ShipTypeID != 587 || len(saved[0].Items) != 1 || saved[0].Items[0].Flag != "HiSlot0" {
+		t.Fatalf("Unexpected fittings: %+v", saved)
+	}
+
+	id, err := e.CreateFitting(90000001, saved[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 2 {
+		t.Fatalf("Expected fitting ID 2, got %d", id)
+	}
+	expected := `POST /latest/characters/90000001/fittings/ {"name":"Rifter","description":"","ship_type_id":587,"items":[{"type_id":2881,"flag":"HiSlot0","quantity":1}]}`
+	if requests[1] != expected {
+		t.Fatalf("Expected %q, got %q", expected, requests[1])
+	}
+
+	if err := e.DeleteFitting(90000001, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Fittings(90000001); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 4 {
+		t.Fatalf("Expected the cached fittings to be dropped after changes, got %q", requests)
+	}
+}
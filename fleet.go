@@ -0,0 +1,109 @@
+package goesi
+
+import (
+	"fmt"
+	"time"
+)
+
+// FleetInfo is the settings of a fleet
+type FleetInfo struct {
+	FleetID        int64  `json:"-"`
+	IsFreeMove     bool   `json:"is_free_move"`
+	IsRegistered   bool   `json:"is_registered"`
+	IsVoiceEnabled bool   `json:"is_voice_enabled"`
+	MOTD           string `json:"motd"`
+}
+
+// FleetMember is a character in a fleet
+type FleetMember struct {
+	CharacterID    int32     `json:"character_id"`
+	JoinTime       time.Time `json:"join_time"`
+	Role           string    `json:"role"`
+	RoleName       string    `json:"role_name"`
+	ShipTypeID     int32     `json:"ship_type_id"`
+	SolarSystemID  int32     `json:"solar_system_id"`
+	StationID      int64     `json:"station_id"`
+	SquadID        int64     `json:"squad_id"`
+	WingID         int64     `json:"wing_id"`
+	TakesFleetWarp bool      `json:"takes_fleet_warp"`
+}
+
+// UnmarshalJSON decodes the fleet member, parsing their join time with ParseESITime
+func (m *FleetMember) UnmarshalJSON(data []byte) error {
+	type fleetMember FleetMember
+	return decodeWithTimes(data, (*fleetMember)(m), map[string]*time.Time{
+		"join_time": &m.JoinTime,
+	})
+}
+
+// Fleet roles, for FleetInvite's Role
+const (
+	FleetRoleCommander      = "fleet_commander"
+	FleetRoleWingCommander  = "wing_commander"
+	FleetRoleSquadCommander = "squad_commander"
+	FleetRoleSquadMember    = "squad_member"
+)
+
+// FleetInvite is an invitation for a character to join a fleet. Set WingID for
+// wing commanders, and both WingID and SquadID for squad commanders and members;
+// a squad member without them is put wherever there is room.
+type FleetInvite struct {
+	CharacterID int32  `json:"character_id"`
+	Role        string `json:"role"`
+	WingID      int64  `json:"wing_id,omitempty"`
+	SquadID     int64  `json:"squad_id,omitempty"`
+}
+
+// FleetUpdate is the fleet settings to change; settings left nil are not changed
+type FleetUpdate struct {
+	IsFreeMove *bool   `json:"is_free_move,omitempty"`
+	MOTD       *string `json:"motd,omitempty"`
+}
+
+// Fleet fetches the settings of the fleet. Needs the esi-fleets.read_fleet.v1 scope
+// and the character to be the fleet's boss.
+func (e *ESI) Fleet(fleetID int64) (*FleetInfo, error) {
+	data, err := e.Get("fleets/%d", fleetID)
+	if err != nil {
+		return nil, err
+	}
+	var info FleetInfo
+	if err := decode(data, &info); err != nil {
+		e.log.Error("Error parsing fleet response", "fleetID", fleetID, "error", err)
+		return nil, err
+	}
+	info.FleetID = fleetID
+	return &info, nil
+}
+
+// FleetMembers fetches the members of the fleet. Needs the esi-fleets.read_fleet.v1 scope.
+func (e *ESI) FleetMembers(fleetID int64) ([]FleetMember, error) {
+	data, err := e.Get("fleets/%d/members", fleetID)
+	if err != nil {
+		return nil, err
+	}
+	var members []FleetMember
+	if err := decode(data, &members); err != nil {
+		e.log.Error("Error parsing fleet members response", "fleetID", fleetID, "error", err)
+		return nil, err
+	}
+	return members, nil
+}
+
+// UpdateFleet changes the settings of the fleet. Needs the esi-fleets.write_fleet.v1 scope.
+func (e *ESI) UpdateFleet(fleetID int64, update FleetUpdate) error {
+	_, err := e.PutJSON(fmt.Sprintf("fleets/%d", fleetID), update)
+	return err
+}
+
+// InviteToFleet invites a character to the fleet. Needs the esi-fleets.write_fleet.v1 scope.
+func (e *ESI) InviteToFleet(fleetID int64, invite FleetInvite) error {
+	_, err := e.PostJSON(fmt.Sprintf("fleets/%d/members", fleetID), invite)
+	return err
+}
+
+// KickFromFleet removes a character from the fleet. Needs the esi-fleets.write_fleet.v1 scope.
+func (e *ESI) KickFromFleet(fleetID int64, characterID int32) error {
+	_, err := e.Delete(fmt.Sprintf("fleets/%d/members/%d", fleetID, characterID))
+	return err
+}
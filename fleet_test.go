@@ -0,0 +1,66 @@
+package goesi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestFleet(t *testing.T) {
+	var requests []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		var body []byte
+		if req.Body != nil {
+			body, _ = ioutil.ReadAll(req.Body)
+		}
+		requests = append(requests, req.Method+" "+req.URL.Path+" "+string(body))
+		switch req.Method + " " + req.URL.Path {
+		case "GET /latest/fleets/1234/":
+			return stubResponse(200, `{"is_free_move": true, "motd": "Welcome"}`), nil
+		case "GET /latest/fleets/1234/members/":
+			return stubResponse(200, `[{"character_id": 5, "role": "fleet_commander", "join_time": "2020-01-02T03:04:05Z", "wing_id": -1}]`), nil
+		}
+		return stubResponse(204, ""), nil
+	})
+
+	info, err := e.Fleet(1234)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.FleetID != 1234 || !info.IsFreeMove || info.MOTD != "Welcome" {
+		t.Fatalf("Unexpected fleet: %+v", info)
+	}
+	members, err := e.FleetMembers(1234)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || members[0].Role != FleetRoleCommander || members[0].WingID != -1 {
+		t.Fatalf("Unexpected members: %+v", members)
+	}
+
+	freeMove := false
+	if err := e.UpdateFleet(1234, FleetUpdate{IsFreeMove: &freeMove}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.InviteToFleet(1234, FleetInvite{CharacterID: 6, Role: FleetRoleSquadMember}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.KickFromFleet(1234, 6); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"GET /latest/fleets/1234/ ",
+		"GET /latest/fleets/1234/members/ ",
+		`PUT /latest/fleets/1234/ {"is_free_move":false}`,
+		`POST /latest/fleets/1234/members/ {"character_id":6,"role":"squad_member"}`,
+		"DELETE /latest/fleets/1234/members/6/ ",
+	}
+	if len(requests) != len(expected) {
+		t.Fatalf("Expected %d requests, got %q", len(expected), requests)
+	}
+	for i := range expected {
+		if requests[i] != expected[i] {
+			t.Fatalf("Expected request '%s', got '%s'", expected[i], requests[i])
+		}
+	}
+}
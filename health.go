@@ -0,0 +1,64 @@
+package goesi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+var (
+	// ErrESIUnavailable is returned by HealthCheck when ESI or the SSO can't be reached
+	// or responds with an error of its own
+	ErrESIUnavailable = errors.New("ESI is unavailable")
+	// ErrTokenInvalid is returned by HealthCheck when the SSO rejects the access token
+	ErrTokenInvalid = errors.New("access token is not valid")
+)
+
+// HealthCheck checks that ESI can be reached by calling the status route and, if an
+// access token is set, that the SSO still accepts it. The error wraps ErrESIUnavailable
+// or ErrTokenInvalid, along with the error of the call that failed. Neither call is
+// served from the cache.
+func (e *ESI) HealthCheck(ctx context.Context) error {
+	if _, err := e.Get("status", WithContext(ctx), NoCache()); err != nil {
+		e.log.Warn("Health check could not reach ESI", "error", err)
+		return fmt.Errorf("%w: %w", ErrESIUnavailable, err)
+	}
+	if e.AccessToken == "" {
+		return nil
+	}
+	if err := e.verifyToken(ctx); err != nil {
+		var ssoErr *SSOError
+		if errors.As(err, &ssoErr) && ssoErr.StatusCode >= 400 && ssoErr.StatusCode < 500 {
+			e.log.Warn("Health check found the access token is not valid", "error", err)
+			return fmt.Errorf("%w: %w", ErrTokenInvalid, err)
+		}
+		e.log.Warn("Health check could not reach the SSO", "error", err)
+		return fmt.Errorf("%w: %w", ErrESIUnavailable, err)
+	}
+	return nil
+}
+
+// verifyToken asks the SSO whether the access token is valid, returning an *SSOError if it isn't
+func (e *ESI) verifyToken(ctx context.Context) error {
+	req, err := e.newRequest("GET", VerifyURL, nil)
+	if err != nil {
+		return err
+	}
+	setupHeaders(e, req)
+	req, done := requestOptions{ctx: ctx}.apply(req)
+	defer done()
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return &SSOError{URL: VerifyURL, Err: err}
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return &SSOError{URL: VerifyURL, Err: err}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newSSOError(VerifyURL, resp, body)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package goesi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestHealthCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		token        string
+		statusCode   int
+		verifyStatus int
+		verifyType   string
+		expected     error
+	}{
+		{"healthy without a token", "", 200, 0, "", nil},
+		{"healthy with a token", "token", 200, 200, "", nil},
+		{"ESI down", "token", 503, 200, "", ErrESIUnavailable},
+		{"token rejected", "token", 200, 401, "", ErrTokenInvalid},
+		{"token rejected with a text body", "token", 200, 401, "text/plain", ErrTokenInvalid},
+		{"SSO down", "token", 200, 502, "", ErrESIUnavailable},
+	}
+	for _, test := range tests {
+		e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+			if req.URL.String() == VerifyURL {
+				if test.verifyStatus == 0 {
+					t.Fatalf("%s: unexpected call to verify the token", test.name)
+				}
+				if test.verifyType != "" {
+					return stubResponse(test.verifyStatus, "Unauthorized", "Content-Type", test.verifyType), nil
+				}
+				return stubResponse(test.verifyStatus, `{"CharacterID": 90000001}`), nil
+			}
+			return stubResponse(test.statusCode, `{"players": 30000}`), nil
+		})
+		e.AccessToken = test.token
+		err := e.HealthCheck(context.Background())
+		if test.expected == nil && err != nil {
+			t.Fatalf("%s: unexpected error %v", test.name, err)
+		}
+		if test.expected != nil && !errors.Is(err, test.expected) {
+			t.Fatalf("%s: expected %v, got %v", test.name, test.expected, err)
+		}
+	}
+}
+
+func TestHealthCheckShutdown(t *testing.T) {
+	started := make(chan struct{})
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if req.URL.String() != VerifyURL {
+			return stubResponse(200, `{"players": 30000}`), nil
+		}
+		close(started)
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+	e.AccessToken = "token"
+	done := make(chan error)
+	go func() {
+		done <- e.HealthCheck(context.Background())
+	}()
+	<-started
+	e.Shutdown()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected Shutdown to cancel the token check, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package goesi
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// IndustryJob is a manufacturing, research, copying, invention, or reaction job. Dates
+// that haven't happened, like CompletedDate for a job that's still running, are the zero time.
+type IndustryJob struct {
+	JobID                int32     `json:"job_id"`
+	InstallerID          int32     `json:"installer_id"`
+	FacilityID           int64     `json:"facility_id"`
+	StationID            int64     `json:"station_id"`
+	ActivityID           int32     `json:"activity_id"`
+	BlueprintID          int64     `json:"blueprint_id"`
+	BlueprintTypeID      int32     `json:"blueprint_type_id"`
+	BlueprintLocationID  int64     `json:"blueprint_location_id"`
+	OutputLocationID     int64     `json:"output_location_id"`
+	ProductTypeID        int32     `json:"product_type_id"`
+	Runs                 int32     `json:"runs"`
+	LicensedRuns         int32     `json:"licensed_runs"`
+	SuccessfulRuns       int32     `json:"successful_runs"`
+	Probability          float64   `json:"probability"`
+	Cost                 float64   `json:"cost"`
+	Status               string    `json:"status"`
+	Duration             int32     `json:"duration"`
+	StartDate            time.Time `json:"start_date"`
+	EndDate              time.Time `json:"end_date"`
+	PauseDate            time.Time `json:"pause_date"`
+	CompletedDate        time.Time `json:"completed_date"`
+	CompletedCharacterID int32     `json:"completed_character_id"`
+}
+
+// UnmarshalJSON decodes the job, parsing its dates with ParseESITime
+func (j *IndustryJob) UnmarshalJSON(data []byte) error {
+	type industryJob IndustryJob
+	return decodeWithTimes(data, (*industryJob)(j), map[string]*time.Time{
+		"start_date":     &j.StartDate,
+		"end_date":       &j.EndDate,
+		"pause_date":     &j.PauseDate,
+		"completed_date": &j.CompletedDate,
+	})
+}
+
+// MiningObserver is a structure, like a refinery, that records what's mined around it
+type MiningObserver struct {
+	ObserverID   int64  `json:"observer_id"`
+	ObserverType string `json:"observer_type"`
+	// LastUpdated is the day the observer last recorded mining
+	LastUpdated time.Time `json:"last_updated"`
+}
+
+// UnmarshalJSON decodes the observer, parsing its date with ParseESITime
+func (o *MiningObserver) UnmarshalJSON(data []byte) error {
+	type miningObserver MiningObserver
+	return decodeWithTimes(data, (*miningObserver)(o), map[string]*time.Time{
+		"last_updated": &o.LastUpdated,
+	})
+}
+
+// IndustryJobs fetches all pages of the character's industry jobs that are running or
+// waiting to be delivered, and if includeCompleted is set, those that have been delivered
+// or cancelled as well. The access token needs the esi-industry.read_character_jobs.v1 scope.
+func (e *ESI) IndustryJobs(characterID int32, includeCompleted bool) ([]IndustryJob, error) {
+	params := url.Values{"include_completed": {strconv.FormatBool(includeCompleted)}}
+	data, err := e.GetAllPages("characters/%d/industry/jobs", characterID, WithParams(params))
+	if err != nil {
+		return nil, err
+	}
+	var jobs []IndustryJob
+	if err := decode(data, &jobs); err != nil {
+		e.log.Error("Error parsing industry jobs response", "characterID", characterID, "error", err)
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// CorporationMiningObservers fetches all pages of the corporation's mining observers. The
+// access token needs the esi-industry.read_corporation_mining.v1 scope, and its character
+// the Accountant role.
+func (e *ESI) CorporationMiningObservers(corpID int32) ([]MiningObserver, error) {
+	data, err := e.GetAllPages("corporation/%d/mining/observers", corpID)
+	if err != nil {
+		return nil, err
+	}
+	var observers []MiningObserver
+	if err := decode(data, &observers); err != nil {
+		e.log.Error("Error parsing mining observers response", "corporationID", corpID, "error", err)
+		return nil, err
+	}
+	return observers, nil
+}
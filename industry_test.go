@@ -0,0 +1,54 @@
+package goesi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestIndustryJobs(t *testing.T) {
+	var queries []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(req.URL.Path, "/characters/90000001/industry/jobs/") {
+			t.Fatalf("Unexpected request to %s", req.URL)
+		}
+		queries = append(queries, req.URL.Query().Get("include_completed"))
+		return stubResponse(200, `[{"job_id": 1, "activity_id": 1, "blueprint_type_id": 691, "product_type_id": 587, "runs": 10, "cost": 118.01, "status": "active", "duration": 548, "start_date": "2020-01-02T03:04:05Z", "end_date": "2020-01-02T03:13:13Z"}]`), nil
+	})
+	jobs, err := e.IndustryJobs(90000001, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].ProductTypeID != 587 || jobs[0].Status != "active" || jobs[0].EndDate.Minute() != 13 || !jobs[0].CompletedDate.IsZero() {
+		t.Fatalf("Unexpected jobs: %+v", jobs)
+	}
+	if _, err := e.IndustryJobs(90000001, true); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(queries, ",") != "false,true" {
+		t.Fatalf("Expected include_completed to be sent, got %q", queries)
+	}
+}
+
+func TestCorporationMiningObservers(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(req.URL.Path, "/corporation/98000001/mining/observers/") {
+			t.Fatalf("Unexpected request to %s", req.URL)
+		}
+		switch req.URL.Query().Get("page") {
+		case "1":
+			return stubResponse(200, `[{"observer_id": 1021975535893, "observer_type": "structure", "last_updated": "2020-01-02"}]`, PagesHeader, "2"), nil
+		case "2":
+			return stubResponse(200, `[{"observer_id": 1021975535894, "observer_type": "structure", "last_updated": "2020-01-03"}]`, PagesHeader, "2"), nil
+		}
+		t.Fatalf("Unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	observers, err := e.CorporationMiningObservers(98000001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(observers) != 2 || observers[0].ObserverType != "structure" || observers[0].LastUpdated.Day() != 2 || observers[1].LastUpdated.Day() != 3 {
+		t.Fatalf("Expected the observers from both pages, got %+v", observers)
+	}
+}
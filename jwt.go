@@ -0,0 +1,373 @@
+package goesi
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JWKSURL is the URL of the SSO's JSON Web Key Set, used to verify token signatures
+const JWKSURL = "https://login.eveonline.com/oauth/jwks"
+
+// tokenAudience is the audience that every access token from the SSO is issued for
+const tokenAudience = "EVE Online"
+
+// tokenIssuers are the issuer values the SSO puts in access tokens
+var tokenIssuers = []string{"login.eveonline.com", "https://login.eveonline.com"}
+
+var (
+	// ErrInvalidToken is returned when the access token is malformed or its signature does not verify
+	ErrInvalidToken = errors.New("access token is not a valid signed JWT")
+	// ErrTokenExpired is returned when the access token's expiry has passed
+	ErrTokenExpired = errors.New("access token has expired")
+	// ErrTokenIssuer is returned when the access token was not issued by the EVE SSO
+	ErrTokenIssuer = errors.New("access token was not issued by the EVE SSO")
+	// ErrTokenAudience is returned when the access token was not issued for EVE Online
+	ErrTokenAudience = errors.New("access token audience does not include EVE Online")
+	// ErrTokenClientAudience is returned when the access token's audience doesn't include
+	// the ClientID, as it was issued for a different app
+	ErrTokenClientAudience = errors.New("access token audience does not include the client ID")
+	// ErrTokenAuthorizedParty is returned when the access token's authorized party isn't the
+	// ClientID, as a different app asked for it
+	ErrTokenAuthorizedParty = errors.New("access token authorized party is not the client ID")
+)
+
+// TokenClaims are the verified contents of an SSO access token
+type TokenClaims struct {
+	Subject         string
+	CharacterID     int32
+	Name            string
+	Owner           string
+	Issuer          string
+	Audience        []string
+	AuthorizedParty string
+	Scopes          []string
+	Expires         time.Time
+	IssuedAt        time.Time
+}
+
+// jwtHeader is the header segment of a JWT
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// jwtClaims is the payload segment of an SSO JWT as it comes over the wire
+type jwtClaims struct {
+	Subject         string          `json:"sub"`
+	Name            string          `json:"name"`
+	Owner           string          `json:"owner"`
+	Issuer          string          `json:"iss"`
+	Audience        json.RawMessage `json:"aud"`
+	AuthorizedParty string          `json:"azp"`
+	Scopes          json.RawMessage `json:"scp"`
+	Expires         int64           `json:"exp"`
+	IssuedAt        int64           `json:"iat"`
+}
+
+// stringOrSlice parses a JSON value that can be either a single string or an array of strings
+func stringOrSlice(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var one string
+	if err := json.Unmarshal(raw, &one); err == nil {
+		return []string{one}, nil
+	}
+	var many []string
+	if err := json.Unmarshal(raw, &many); err != nil {
+		return nil, err
+	}
+	return many, nil
+}
+
+// VerifyTokenSignature verifies the signature of the access token against the SSO's
+// published keys, checks the issuer, audience, and expiry, and returns the token's claims.
+// It also checks that the token was issued to this app: that its audience includes the
+// ClientID and its authorized party is the ClientID, so that a token another app got from
+// the SSO isn't accepted. Each check that fails has an error of its own, like ErrTokenIssuer.
+// Use this rather than reading the token's contents directly whenever the claims are
+// trusted for authorization decisions.
+func (e *ESI) VerifyTokenSignature() (*TokenClaims, error) {
+	e.log.Debug("Verifying access token signature")
+	if e.jwks == nil {
+		e.jwks = &jwksCache{}
+	}
+	if e.ClientID == "" {
+		return nil, ErrMissingClientID
+	}
+	return verifyJWT(e.AccessToken, e.ClientID, func(kid string) (*rsa.PublicKey, error) {
+		return e.jwks.key(e, kid)
+	}, e.now())
+}
+
+// ScopesChanged returns the scopes that the access token gained and lost with the last
+// RefreshAccessToken, compared to the token it replaced, like when the user revoked some of
+// them. Features needing a removed scope can be turned off rather than getting 403 Forbidden
+// from ESI. Both are empty if nothing changed, no refresh has been made since the last
+// Authenticate, or either token's scopes couldn't be read.
+func (e *ESI) ScopesChanged() (added, removed []string) {
+	return e.scopesAdded, e.scopesRemoved
+}
+
+// unverifiedClaims returns the access token's claims without verifying it, so they're
+// only to be used for things like comparing tokens, not to make authorization decisions
+func unverifiedClaims(token string) (*TokenClaims, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, false
+	}
+	var raw jwtClaims
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, false
+	}
+	claims, err := raw.toClaims()
+	if err != nil {
+		return nil, false
+	}
+	return claims, true
+}
+
+// scopeChanges returns the scopes in the current token that aren't in the previous one,
+// and those in the previous one that aren't in the current one
+func scopeChanges(previous, current string) (added, removed []string) {
+	beforeClaims, ok := unverifiedClaims(previous)
+	if !ok {
+		return nil, nil
+	}
+	afterClaims, ok := unverifiedClaims(current)
+	if !ok {
+		return nil, nil
+	}
+	before, after := beforeClaims.Scopes, afterClaims.Scopes
+	for _, scope := range after {
+		if !containsString(before, scope) {
+			added = append(added, scope)
+		}
+	}
+	for _, scope := range before {
+		if !containsString(after, scope) {
+			removed = append(removed, scope)
+		}
+	}
+	return added, removed
+}
+
+// verifyJWT checks the signature and standard claims of an RS256 JWT, and that it was issued
+// to the client ID, using keyFor to find the signing key
+func verifyJWT(token, clientID string, keyFor func(kid string) (*rsa.PublicKey, error), now time.Time) (*TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if header.Algorithm != "RS256" {
+		return nil, fmt.Errorf("%w: unsupported algorithm '%s'", ErrInvalidToken, header.Algorithm)
+	}
+	key, err := keyFor(header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var raw jwtClaims
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, ErrInvalidToken
+	}
+	claims, err := raw.toClaims()
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !containsString(tokenIssuers, claims.Issuer) {
+		return nil, ErrTokenIssuer
+	}
+	if !containsString(claims.Audience, tokenAudience) {
+		return nil, ErrTokenAudience
+	}
+	if !containsString(claims.Audience, clientID) {
+		return nil, ErrTokenClientAudience
+	}
+	if claims.AuthorizedParty != clientID {
+		return nil, ErrTokenAuthorizedParty
+	}
+	if !claims.Expires.After(now) {
+		return nil, ErrTokenExpired
+	}
+	return claims, nil
+}
+
+// toClaims converts the wire format of the claims into TokenClaims
+func (c jwtClaims) toClaims() (*TokenClaims, error) {
+	audience, err := stringOrSlice(c.Audience)
+	if err != nil {
+		return nil, err
+	}
+	scopes, err := stringOrSlice(c.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	claims := &TokenClaims{
+		Subject:         c.Subject,
+		Name:            c.Name,
+		Owner:           c.Owner,
+		Issuer:          c.Issuer,
+		Audience:        audience,
+		AuthorizedParty: c.AuthorizedParty,
+		Scopes:          scopes,
+		Expires:         time.Unix(c.Expires, 0).UTC(),
+		IssuedAt:        time.Unix(c.IssuedAt, 0).UTC(),
+	}
+	// the subject is in the form "CHARACTER:EVE:<id>"
+	if i := strings.LastIndex(c.Subject, ":"); i != -1 {
+		if id, err := strconv.ParseInt(c.Subject[i+1:], 10, 32); err == nil {
+			claims.CharacterID = int32(id)
+		}
+	}
+	return claims, nil
+}
+
+// decodeSegment decodes a base64url JSON segment of a JWT into v
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// containsString returns whether the slice contains the string
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// jwksCache holds the SSO's signing keys, refetching them when they get old
+// or when a token is signed with a key that isn't known yet.
+type jwksCache struct {
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+const (
+	// jwksMaxAge is how long the fetched keys are used before fetching them again
+	jwksMaxAge = 24 * time.Hour
+	// jwksMinRefetch limits how often an unknown key ID can trigger a refetch
+	jwksMinRefetch = time.Minute
+)
+
+// key returns the signing key with the ID, fetching the key set if needed
+func (j *jwksCache) key(e *ESI, kid string) (*rsa.PublicKey, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	key, ok := j.keys[kid]
+	age := e.now().Sub(j.fetched)
+	if ok && age < jwksMaxAge {
+		return key, nil
+	}
+	if !ok && j.keys != nil && age < jwksMinRefetch {
+		return nil, fmt.Errorf("%w: unknown signing key '%s'", ErrInvalidToken, kid)
+	}
+	keys, err := fetchJWKS(e)
+	if err != nil {
+		return nil, err
+	}
+	j.keys = keys
+	j.fetched = e.now()
+	key, ok = keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown signing key '%s'", ErrInvalidToken, kid)
+	}
+	return key, nil
+}
+
+// jwk is a single key in a JSON Web Key Set
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+// fetchJWKS downloads the SSO's key set and returns its RSA keys by key ID
+func fetchJWKS(e *ESI) (map[string]*rsa.PublicKey, error) {
+	e.log.Info("Fetching SSO signing keys", "url", JWKSURL)
+	req, err := e.newRequest("GET", JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("User-Agent", e.UserAgent)
+	req.Header.Add("Accept", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		e.log.Error("Error fetching SSO signing keys", "url", JWKSURL, "error", err)
+		return nil, &SSOError{URL: JWKSURL, Err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		e.log.Error("Error fetching SSO signing keys", "url", JWKSURL, "status", resp.StatusCode)
+		return nil, newSSOError(JWKSURL, resp, body)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.KeyType != "RSA" {
+			continue
+		}
+		key, err := k.rsaKey()
+		if err != nil {
+			e.log.Warn("Skipping invalid SSO signing key", "keyID", k.KeyID, "error", err)
+			continue
+		}
+		keys[k.KeyID] = key
+	}
+	return keys, nil
+}
+
+// rsaKey converts the JWK into an RSA public key
+func (k jwk) rsaKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.Modulus)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.Exponent)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
@@ -0,0 +1,142 @@
+package goesi
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func signTestJWT(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "JWT-Signature-Key", "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerifyJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFor := func(kid string) (*rsa.PublicKey, error) {
+		return &key.PublicKey, nil
+	}
+	now := time.Now()
+	claims := func(iss string, aud interface{}, exp time.Time) map[string]interface{} {
+		return map[string]interface{}{
+			"sub":  "CHARACTER:EVE:90000001",
+			"name": "Test Character",
+			"iss":  iss,
+			"aud":  aud,
+			"azp":  "clientID",
+			"scp":  "esi-skills.read_skills.v1",
+			"exp":  exp.Unix(),
+		}
+	}
+
+	valid := signTestJWT(t, key, claims("login.eveonline.com", []string{"clientID", "EVE Online"}, now.Add(time.Minute)))
+	verified, err := verifyJWT(valid, "clientID", keyFor, now)
+	if err != nil {
+		t.Fatalf("Expected valid token, got error: %s", err)
+	}
+	if verified.CharacterID != 90000001 || len(verified.Scopes) != 1 {
+		t.Fatalf("Unexpected claims: %+v", verified)
+	}
+
+	tampered := valid[:len(valid)-4] + "AAAA"
+	if _, err := verifyJWT(tampered, "clientID", keyFor, now); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Expected ErrInvalidToken for tampered token, got %v", err)
+	}
+	expired := signTestJWT(t, key, claims("login.eveonline.com", []string{"clientID", "EVE Online"}, now.Add(-time.Minute)))
+	if _, err := verifyJWT(expired, "clientID", keyFor, now); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("Expected ErrTokenExpired, got %v", err)
+	}
+	wrongIssuer := signTestJWT(t, key, claims("example.com", "EVE Online", now.Add(time.Minute)))
+	if _, err := verifyJWT(wrongIssuer, "clientID", keyFor, now); !errors.Is(err, ErrTokenIssuer) {
+		t.Fatalf("Expected ErrTokenIssuer, got %v", err)
+	}
+	wrongAudience := signTestJWT(t, key, claims("login.eveonline.com", "clientID", now.Add(time.Minute)))
+	if _, err := verifyJWT(wrongAudience, "clientID", keyFor, now); !errors.Is(err, ErrTokenAudience) {
+		t.Fatalf("Expected ErrTokenAudience, got %v", err)
+	}
+	if _, err := verifyJWT(valid, "otherClientID", keyFor, now); !errors.Is(err, ErrTokenClientAudience) {
+		t.Fatalf("Expected ErrTokenClientAudience, got %v", err)
+	}
+	otherParty := claims("login.eveonline.com", []string{"clientID", "EVE Online"}, now.Add(time.Minute))
+	otherParty["azp"] = "otherClientID"
+	if _, err := verifyJWT(signTestJWT(t, key, otherParty), "clientID", keyFor, now); !errors.Is(err, ErrTokenAuthorizedParty) {
+		t.Fatalf("Expected ErrTokenAuthorizedParty, got %v", err)
+	}
+}
+
+func TestScopesChanged(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := func(scopes ...string) string {
+		return signTestJWT(t, key, map[string]interface{}{"sub": "CHARACTER:EVE:90000001", "scp": scopes})
+	}
+	next := token("esi-skills.read_skills.v1", "esi-mail.read_mail.v1")
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		return stubResponse(200, fmt.Sprintf(`{"access_token": "%s", "expires_in": 1199}`, next)), nil
+	})
+	e.AccessToken = token("esi-skills.read_skills.v1", "esi-wallet.read_character_wallet.v1")
+	e.RefreshToken = "refresh"
+	if added, removed := e.ScopesChanged(); added != nil || removed != nil {
+		t.Fatalf("Expected no changes before a refresh, got %v and %v", added, removed)
+	}
+	if err := e.RefreshAccessToken(); err != nil {
+		t.Fatal(err)
+	}
+	added, removed := e.ScopesChanged()
+	if !reflect.DeepEqual(added, []string{"esi-mail.read_mail.v1"}) || !reflect.DeepEqual(removed, []string{"esi-wallet.read_character_wallet.v1"}) {
+		t.Fatalf("Unexpected changes: added %v, removed %v", added, removed)
+	}
+	if err := e.RefreshAccessToken(); err != nil {
+		t.Fatal(err)
+	}
+	if added, removed := e.ScopesChanged(); added != nil || removed != nil {
+		t.Fatalf("Expected no changes after an identical refresh, got %v and %v", added, removed)
+	}
+}
+
+func TestUserAgentCharacter(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var agents []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		agents = append(agents, req.Header.Get("User-Agent"))
+		return stubResponse(200, `{}`), nil
+	})
+	e.UserAgent = "My App (someone@example.com)"
+	e.UserAgentCharacter = true
+	e.AccessToken = signTestJWT(t, key, map[string]interface{}{"sub": "CHARACTER:EVE:90000001"})
+	e.Get("characters/90000001/wallet")
+	e.GetPublic("status")
+	e.AccessToken = "not-a-jwt"
+	e.Get("characters/90000001/wallet")
+	expected := []string{"My App (someone@example.com) (character: 90000001)", "My App (someone@example.com)", "My App (someone@example.com)"}
+	if !reflect.DeepEqual(agents, expected) {
+		t.Fatalf("Expected User-Agents %q, got %q", expected, agents)
+	}
+}
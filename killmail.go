@@ -0,0 +1,87 @@
+package goesi
+
+import (
+	"fmt"
+	"time"
+)
+
+// A KillmailRef identifies a killmail by its ID and hash, as returned by
+// the killmails/recent routes and by killboards
+type KillmailRef struct {
+	ID   int32  `json:"killmail_id"`
+	Hash string `json:"killmail_hash"`
+}
+
+// A Killmail is the record of a ship or structure being destroyed
+type Killmail struct {
+	KillmailID    int32              `json:"killmail_id"`
+	KillmailTime  time.Time          `json:"killmail_time"`
+	SolarSystemID int32              `json:"solar_system_id"`
+	MoonID        int32              `json:"moon_id"`
+	WarID         int32              `json:"war_id"`
+	Victim        KillmailVictim     `json:"victim"`
+	Attackers     []KillmailAttacker `json:"attackers"`
+}
+
+// UnmarshalJSON decodes the killmail, parsing its time with ParseESITime
+func (k *Killmail) UnmarshalJSON(data []byte) error {
+	type killmail Killmail
+	return decodeWithTimes(data, (*killmail)(k), map[string]*time.Time{
+		"killmail_time": &k.KillmailTime,
+	})
+}
+
+// KillmailVictim is the character, corporation, or structure that lost the ship
+type KillmailVictim struct {
+	CharacterID   int32          `json:"character_id"`
+	CorporationID int32          `json:"corporation_id"`
+	AllianceID    int32          `json:"alliance_id"`
+	FactionID     int32          `json:"faction_id"`
+	ShipTypeID    int32          `json:"ship_type_id"`
+	DamageTaken   int32          `json:"damage_taken"`
+	Items         []KillmailItem `json:"items"`
+}
+
+// KillmailAttacker is one of the attackers on a killmail
+type KillmailAttacker struct {
+	CharacterID    int32   `json:"character_id"`
+	CorporationID  int32   `json:"corporation_id"`
+	AllianceID     int32   `json:"alliance_id"`
+	FactionID      int32   `json:"faction_id"`
+	ShipTypeID     int32   `json:"ship_type_id"`
+	WeaponTypeID   int32   `json:"weapon_type_id"`
+	DamageDone     int32   `json:"damage_done"`
+	FinalBlow      bool    `json:"final_blow"`
+	SecurityStatus float64 `json:"security_status"`
+}
+
+// KillmailItem is an item that was fitted or carried by the victim
+type KillmailItem struct {
+	ItemTypeID        int32          `json:"item_type_id"`
+	Flag              int32          `json:"flag"`
+	QuantityDestroyed int64          `json:"quantity_destroyed"`
+	QuantityDropped   int64          `json:"quantity_dropped"`
+	Singleton         int32          `json:"singleton"`
+	Items             []KillmailItem `json:"items"`
+}
+
+// Killmails fetches each of the killmails concurrently, returning them in the same order
+// as the refs. Killmails never change, so they're cached for a year by default (see CacheTTLOverrides).
+func (e *ESI) Killmails(refs []KillmailRef) ([]Killmail, error) {
+	paths := make([]string, len(refs))
+	for i, ref := range refs {
+		paths[i] = fmt.Sprintf("killmails/%d/%s", ref.ID, ref.Hash)
+	}
+	responses, err := e.getAll(paths)
+	if err != nil {
+		return nil, err
+	}
+	killmails := make([]Killmail, len(refs))
+	for i, response := range responses {
+		if err := decode(response, &killmails[i]); err != nil {
+			e.log.Error("Error parsing killmail response", "killmailID", refs[i].ID, "error", err)
+			return nil, err
+		}
+	}
+	return killmails, nil
+}
@@ -0,0 +1,39 @@
+package goesi
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestKillmails(t *testing.T) {
+	var calls int32
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/killmails/1/abc/"):
+			return stubResponse(200, `{"killmail_id": 1, "killmail_time": "2020-01-02T03:04:05Z", "victim": {"ship_type_id": 587}, "attackers": [{"character_id": 5, "final_blow": true}]}`), nil
+		case strings.HasSuffix(req.URL.Path, "/killmails/2/def/"):
+			return stubResponse(200, `{"killmail_id": 2, "victim": {"ship_type_id": 670}}`), nil
+		}
+		t.Fatalf("Unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	refs := []KillmailRef{{1, "abc"}, {2, "def"}}
+	for i := 0; i < 2; i++ {
+		killmails, err := e.Killmails(refs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(killmails) != 2 || killmails[0].Victim.ShipTypeID != 587 || killmails[1].KillmailID != 2 {
+			t.Fatalf("Unexpected killmails: %+v", killmails)
+		}
+		if !killmails[0].Attackers[0].FinalBlow || killmails[0].KillmailTime.Year() != 2020 {
+			t.Fatalf("Unexpected attackers: %+v", killmails[0])
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("Expected the killmails to be cached without an Expires header, got %d calls", calls)
+	}
+}
@@ -0,0 +1,67 @@
+package goesi
+
+import (
+	"fmt"
+	"github.com/op/go-logging"
+	"strings"
+)
+
+// A Logger receives the package's log messages. Each message comes with key-value
+// pairs of context, like the method, URL, status, and duration of a call, or whether
+// a call was served from the cache. Implement this to route the logs into a structured
+// logging library like zap or zerolog, and set it with SetLogger.
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// defaultLogger is the Logger used unless another is set, which writes to the go-logging "goesi" logger
+var defaultLogger Logger = goLoggingLogger{logging.MustGetLogger("goesi")}
+
+// goLoggingLogger is a Logger that writes to a go-logging logger,
+// with the key-value pairs appended to the message as key=value
+type goLoggingLogger struct {
+	l *logging.Logger
+}
+
+func (g goLoggingLogger) Debug(msg string, keyvals ...interface{}) {
+	g.l.Debug("%s", formatKeyvals(msg, keyvals))
+}
+
+func (g goLoggingLogger) Info(msg string, keyvals ...interface{}) {
+	g.l.Info("%s", formatKeyvals(msg, keyvals))
+}
+
+func (g goLoggingLogger) Warn(msg string, keyvals ...interface{}) {
+	g.l.Warning("%s", formatKeyvals(msg, keyvals))
+}
+
+func (g goLoggingLogger) Error(msg string, keyvals ...interface{}) {
+	g.l.Error("%s", formatKeyvals(msg, keyvals))
+}
+
+// formatKeyvals appends the key-value pairs to the message as key=value, quoting values with spaces
+func formatKeyvals(msg string, keyvals []interface{}) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		var value interface{} = "(missing)"
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		s := fmt.Sprint(value)
+		if strings.ContainsAny(s, " \t\n\"") {
+			s = fmt.Sprintf("%q", s)
+		}
+		fmt.Fprintf(&b, " %v=%s", keyvals[i], s)
+	}
+	return b.String()
+}
+
+// SetLogger sets where the package's log messages go. Set it before making any calls.
+func (e *ESI) SetLogger(l Logger) {
+	e.log = l
+	e.cache.log = l
+}
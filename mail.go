@@ -0,0 +1,119 @@
+package goesi
+
+import (
+	"fmt"
+	"github.com/Jeffail/gabs"
+	"strconv"
+	"time"
+)
+
+// MailRecipient is a recipient of an EVE mail: a character, corporation, alliance,
+// or mailing list, as RecipientType says
+type MailRecipient struct {
+	RecipientID   int32  `json:"recipient_id"`
+	RecipientType string `json:"recipient_type"`
+}
+
+// MailHeader is an EVE mail in a character's mailbox, without its body
+type MailHeader struct {
+	MailID     int32           `json:"mail_id"`
+	From       int32           `json:"from"`
+	Subject    string          `json:"subject"`
+	Timestamp  time.Time       `json:"timestamp"`
+	IsRead     bool            `json:"is_read"`
+	Labels     []int32         `json:"labels"`
+	Recipients []MailRecipient `json:"recipients"`
+}
+
+// UnmarshalJSON decodes the mail header, parsing its timestamp with ParseESITime
+func (m *MailHeader) UnmarshalJSON(data []byte) error {
+	type mailHeader MailHeader
+	return decodeWithTimes(data, (*mailHeader)(m), map[string]*time.Time{
+		"timestamp": &m.Timestamp,
+	})
+}
+
+// Mail is an EVE mail with its body, which is HTML as written in the game's mail editor
+type Mail struct {
+	MailID     int32           `json:"-"`
+	From       int32           `json:"from"`
+	Subject    string          `json:"subject"`
+	Body       string          `json:"body"`
+	Timestamp  time.Time       `json:"timestamp"`
+	Read       bool            `json:"read"`
+	Labels     []int32         `json:"labels"`
+	Recipients []MailRecipient `json:"recipients"`
+}
+
+// UnmarshalJSON decodes the mail, parsing its timestamp with ParseESITime
+func (m *Mail) UnmarshalJSON(data []byte) error {
+	type mail Mail
+	return decodeWithTimes(data, (*mail)(m), map[string]*time.Time{
+		"timestamp": &m.Timestamp,
+	})
+}
+
+// Mail fetches the headers of all of the character's mail, newest first. ESI returns mail
+// in batches of up to 50 instead of in pages, each batch asked for with the ID of the oldest
+// mail in the last one, so this makes a call per batch with GetAllCursor until there's no
+// more mail. The access token needs the esi-mail.read_mail.v1 scope.
+func (e *ESI) Mail(characterID int32) ([]MailHeader, error) {
+	data, err := e.GetAllCursor("characters/%d/mail", "last_mail_id", oldestMailID, characterID)
+	if err != nil {
+		return nil, err
+	}
+	var headers []MailHeader
+	if err := decode(data, &headers); err != nil {
+		e.log.Error("Error parsing mail response", "characterID", characterID, "error", err)
+		return nil, err
+	}
+	return headers, nil
+}
+
+// oldestMailID returns the ID of the oldest mail in a batch of mail headers, the cursor
+// for the next batch, or whether the batch is empty
+func oldestMailID(batch *gabs.Container) (string, bool) {
+	children, _ := batch.Children()
+	oldest := 0.0
+	for _, child := range children {
+		if id, ok := child.Path("mail_id").Data().(float64); ok && (oldest == 0 || id < oldest) {
+			oldest = id
+		}
+	}
+	if len(children) == 0 {
+		return "", true
+	}
+	if oldest == 0 {
+		return "", false
+	}
+	return strconv.FormatFloat(oldest, 'f', -1, 64), false
+}
+
+// MailBody fetches one of the character's mails with its body. The access token needs
+// the esi-mail.read_mail.v1 scope.
+func (e *ESI) MailBody(characterID, mailID int32) (*Mail, error) {
+	data, err := e.Get("characters/%d/mail/%d", characterID, mailID)
+	if err != nil {
+		return nil, err
+	}
+	var mail Mail
+	if err := decode(data, &mail); err != nil {
+		e.log.Error("Error parsing mail body response", "characterID", characterID, "mailID", mailID, "error", err)
+		return nil, err
+	}
+	mail.MailID = mailID
+	return &mail, nil
+}
+
+// MarkMailRead marks one of the character's mails as read. The cached mail and the cached
+// newest batch of mail headers are dropped, so the next calls see it as read. The access
+// token needs the esi-mail.organize_mail.v1 scope.
+func (e *ESI) MarkMailRead(characterID, mailID int32) error {
+	path := fmt.Sprintf("characters/%d/mail/%d", characterID, mailID)
+	if _, err := e.PutJSON(path, map[string]bool{"read": true}); err != nil {
+		return err
+	}
+	e.cache.remove(e.cacheKey("GET", e.buildURL(path)))
+	e.cache.remove(e.cacheKey("GET", e.buildURL(fmt.Sprintf("characters/%d/mail", characterID))))
+	return nil
+}
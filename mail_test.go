@@ -0,0 +1,79 @@
+package goesi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMail(t *testing.T) {
+	var cursors []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(req.URL.Path, "/characters/90000001/mail/") {
+			t.Fatalf("Unexpected request to %s", req.URL)
+		}
+		cursor := req.URL.Query().Get("last_mail_id")
+		cursors = append(cursors, cursor)
+		switch cursor {
+		case "":
+			var mails []string
+			for id := 150; id > 100; id-- {
+				mails = append(mails, `{"mail_id": `+strconv.Itoa(id)+`, "from": 90000002, "subject": "Hi", "timestamp": "2020-01-02T03:04:05Z"}`)
+			}
+			return stubResponse(200, "["+strings.Join(mails, ",")+"]"), nil
+		case "101":
+			return stubResponse(200, `[{"mail_id": 100, "is_read": true, "labels": [1], "recipients": [{"recipient_id": 90000001, "recipient_type": "character"}]}]`), nil
+		}
+		return stubResponse(200, `[]`), nil
+	})
+	headers, err := e.Mail(90000001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(cursors, ",") != ",101,100" {
+		t.Fatalf("Expected each batch to be asked for from the last one's oldest mail, got %q", cursors)
+	}
+	if len(headers) != 51 || headers[0].MailID != 150 || headers[0].Timestamp.Year() != 2020 {
+		t.Fatalf("Expected the mail from both batches, got %d headers starting with %+v", len(headers), headers[0])
+	}
+	if last := headers[50]; last.MailID != 100 || !last.IsRead || len(last.Recipients) != 1 || last.Recipients[0].RecipientType != "character" {
+		t.Fatalf("Unexpected last header: %+v", last)
+	}
+}
+
+func TestMailBodyAndMarkRead(t *testing.T) {
+	expires := time.Now().UTC().Add(time.Hour).Format(http.TimeFormat)
+	read := false
+	var puts []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(req.URL.Path, "/characters/90000001/mail/150/") {
+			t.Fatalf("Unexpected request to %s", req.URL)
+		}
+		if req.Method == "PUT" {
+			body, _ := ioutil.ReadAll(req.Body)
+			puts = append(puts, string(body))
+			read = true
+			return stubResponse(204, ""), nil
+		}
+		return stubResponse(200, `{"from": 90000002, "subject": "Hi", "body": "<font size=\"12\">Hello</font>", "timestamp": "2020-01-02T03:04:05Z", "read": `+strconv.FormatBool(read)+`}`, "Expires", expires), nil
+	})
+	mail, err := e.MailBody(90000001, 150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mail.MailID != 150 || mail.From != 90000002 || !strings.Contains(mail.Body, "Hello") || mail.Read {
+		t.Fatalf("Unexpected mail: %+v", mail)
+	}
+	if err := e.MarkMailRead(90000001, 150); err != nil {
+		t.Fatal(err)
+	}
+	if len(puts) != 1 || puts[0] != `{"read":true}` {
+		t.Fatalf("Expected a PUT marking the mail read, got %q", puts)
+	}
+	if mail, err = e.MailBody(90000001, 150); err != nil || !mail.Read {
+		t.Fatalf("Expected the cached mail to be dropped, got %+v, %v", mail, err)
+	}
+}
@@ -2,34 +2,153 @@ package goesi
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/Jeffail/gabs"
-	"github.com/op/go-logging"
+	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
+	"sync"
+	"time"
 )
 
-var log = logging.MustGetLogger("goesi")
-
 // ESI is the interface for interacting with the EVE Swagger Interface
 type ESI struct {
 	client            *http.Client
 	cache             *Cache
+	breakers          *breakerSet
+	jwks              *jwksCache
+	refreshing        *inflight
+	userAgentWarning  *sync.Once
+	tokenMu           *sync.Mutex
+	swagger           *swaggerCache
+	errorLimit        *errorLimit
+	metrics           *routeMetrics
+	clock             Clock
+	log               Logger
+	compatibilityDate string
+	scopesAdded       []string
+	scopesRemoved     []string
+	ctx               context.Context
+	cancel            context.CancelFunc
 	Version           string
 	ClientID          string
 	ClientSecret      string
 	ClientCallbackURL string
-	UserAgent         string
-	Scope             string
-	AccessToken       string
-	RefreshToken      string
+	// UserAgent is sent with every request. CCP asks for it to describe the app and
+	// how to contact its developer, and may throttle apps with generic user agents.
+	UserAgent    string
+	Scope        string
+	AccessToken  string
+	RefreshToken string
+	// TokenExpiry is when the access token expires, as told by the SSO when it was issued.
+	// It's zero if the token wasn't fetched by this struct.
+	TokenExpiry time.Time
+	// AutoRefresh makes an authenticated call that ESI responds to with 403 Forbidden
+	// refresh the access token and retry the call once, for tokens that expire between
+	// being checked and the call reaching ESI. If the refresh fails, its error is returned.
+	AutoRefresh bool
+	// CharacterID is the ID of the access token's character.
+	// It's filled in from WhoAmI the first time it's needed.
+	CharacterID int32
+	// CachePOST enables caching of POST responses, keyed on the URL and a hash
+	// of the request body. Only enable this if every POST you make is to a route
+	// that is a pure function of its input (like universe/names or universe/ids);
+	// a cached response to a mutating POST would mean the request is never sent.
+	CachePOST bool
+	// BreakerThreshold is the number of consecutive 5xx or timed out calls to ESI
+	// after which the circuit breaker opens and calls fail with ErrCircuitOpen.
+	// Each host in BaseURLs has a circuit breaker of its own.
+	// Set to 0 to disable the circuit breaker.
+	BreakerThreshold int
+	// BreakerCooldown is how long the circuit breaker stays open before
+	// letting a request through to test whether ESI has recovered
+	BreakerCooldown time.Duration
+	// InvalidateOnWrite is called after each successful POST, PUT, or DELETE with
+	// the method and path of the call. It returns the paths, in the same form as
+	// passed to Get, whose cached responses are now stale and should be dropped.
+	InvalidateOnWrite func(method, path string) []string
+	// CacheTTLOverrides sets how long responses are cached for routes, keyed by
+	// route prefix like "universe/types", overriding the response's Expires header.
+	// The override with the longest matching prefix is used.
+	CacheTTLOverrides map[string]time.Duration
+	// MaxConcurrency is the most calls that methods making many calls, like Types,
+	// make at the same time
+	MaxConcurrency int
+	// CacheKeyFunc, if set, returns the cache key for a call from its method and full URL.
+	// Use it to normalize URLs that differ only in ways that don't change the response,
+	// like by dropping a cache-busting query param, so that they share a cache entry.
+	// By default the full URL is the key.
+	CacheKeyFunc func(method, url string) string
+	// MaxRetries is how many times a call that ESI rate limits (status 420 or 429)
+	// is retried, after waiting as long as the response's Retry-After header says.
+	// Calls that would have to wait longer than a minute are not retried.
+	// Set to 0 to disable retries.
+	MaxRetries int
+	// UserAgentCharacter appends the ID of the access token's character, from the token's
+	// claims, to the User-Agent of authenticated calls, like "my-app (character: 90000001)",
+	// as CCP asks, so that calls can be traced to the character making them
+	UserAgentCharacter bool
+	// QuietUserAgent stops the warning logged when the first request is made
+	// with UserAgent left as DefaultUserAgent
+	QuietUserAgent bool
+	// CacheNotFound caches 404 responses to GET calls for NotFoundTTL, so that calls for
+	// something that doesn't exist, like a deleted character, return the cached *ESIError
+	// without making a request, and don't use up ESI's error limit.
+	CacheNotFound bool
+	// NotFoundTTL is how long 404 responses are cached when CacheNotFound is set. Keep it
+	// short, as what was missing can appear. 0 means defaultNotFoundTTL (a minute).
+	NotFoundTTL time.Duration
+	// BaseURLs are the top-level URLs, like BaseURL or a mirror's, that GetWithFailover
+	// tries in order. If it's empty, GetWithFailover only uses BaseURL.
+	BaseURLs []string
+	// RouteTimeouts sets how long calls to routes may take, keyed by route prefix like
+	// "markets", in place of the HTTP client's Timeout, so that fast routes can fail fast
+	// and slow bulk routes can be given longer. The timeout with the longest matching prefix
+	// is used; calls to routes without one use the HTTP client's Timeout. A timeout covers
+	// each attempt at the call, including reading the response.
+	RouteTimeouts map[string]time.Duration
+	// OnCache, if set, is called with each thing the cache does for a call: serving it,
+	// missing, finding an expired response, and storing a response. Unlike Stats, this says
+	// which calls were served from the cache, for tracing. It's called on the goroutine
+	// making the call, so it must be quick and safe for concurrent use.
+	OnCache func(event CacheEvent)
+	// MaxResponseBytes is the largest response body that's read from ESI. Bodies that are
+	// longer, like an endless one from a misbehaving proxy, stop being read once they're past
+	// it and the call returns a *ResponseTooLargeError. 0 means there's no limit.
+	MaxResponseBytes int64
+	// ServeStaleOnError makes Get return the expired cached data for a call that fails, if
+	// there is any, rather than just the error, for apps that would rather show old data
+	// than none while ESI is down. Only calls that couldn't be made, timed out on the HTTP
+	// client's Timeout or a RouteTimeouts one, or got a 5xx response fall back to it, not
+	// calls whose own context or deadline ended. The data comes with a *StaleError saying it's expired, so
+	// check for one with errors.As before treating the error as a failure.
+	ServeStaleOnError bool
+	// CollectRouteMetrics keeps counts and latencies of the calls made to each route, for
+	// RouteMetrics, to help with things like setting RouteTimeouts. It's off by default, as
+	// it costs a little time and memory for each call. The metrics are shared with clones.
+	CollectRouteMetrics bool
+	// Backoff is how long Subscribe waits after failed checks in a row. If it's nil,
+	// DefaultBackoff is used.
+	Backoff Backoff
 }
 
+// defaultNotFoundTTL is how long 404 responses are cached when NotFoundTTL isn't set
+const defaultNotFoundTTL = time.Minute
+
 const (
+	// DefaultUserAgent is the UserAgent until it's set to one describing the app
+	DefaultUserAgent = "github.com/Celeo/Goesi"
 	// BaseURL is the top-level URL of ESI
 	BaseURL = "https://esi.tech.ccp.is/"
 	// OauthURL is the URL for making the first OAuth request
@@ -44,29 +163,78 @@ const (
 
 // New creates a new instance of the ESI struct and returns it
 func New(clientID, clientSecret, clientCallbackURL string) ESI {
-	log.Debug("Initializing a new ESI struct")
-	cache := make(Cache)
+	return NewWithOptions(clientID, clientSecret, clientCallbackURL, Options{})
+}
+
+// NewWithOptions creates a new instance of the ESI struct, with its HTTP client configured by the options
+func NewWithOptions(clientID, clientSecret, clientCallbackURL string, opts Options) ESI {
+	defaultLogger.Debug("Initializing a new ESI struct")
+	ctx, cancel := context.WithCancel(context.Background())
 	return ESI{
-		&http.Client{},
-		&cache,
-		"latest",
-		clientID,
-		clientSecret,
-		clientCallbackURL,
-		"github.com/Celeo/Goesi",
-		"",
-		"",
-		"",
+		client:            &http.Client{Transport: newTransport(opts)},
+		cache:             newCache(),
+		breakers:          &breakerSet{},
+		jwks:              &jwksCache{},
+		refreshing:        newInflight(),
+		userAgentWarning:  &sync.Once{},
+		tokenMu:           &sync.Mutex{},
+		swagger:           &swaggerCache{},
+		errorLimit:        &errorLimit{},
+		metrics:           &routeMetrics{},
+		clock:             realClock{},
+		log:               defaultLogger,
+		ctx:               ctx,
+		cancel:            cancel,
+		Version:           "latest",
+		ClientID:          clientID,
+		ClientSecret:      clientSecret,
+		ClientCallbackURL: clientCallbackURL,
+		UserAgent:         DefaultUserAgent,
+		BreakerThreshold:  5,
+		BreakerCooldown:   30 * time.Second,
+		CacheTTLOverrides: defaultCacheTTLOverrides(),
+		MaxConcurrency:    defaultMaxConcurrency,
+		MaxRetries:        defaultMaxRetries,
+	}
+}
+
+// Environment variables read by NewFromEnv
+const (
+	EnvClientID    = "EVE_CLIENT_ID"
+	EnvSecret      = "EVE_CLIENT_SECRET"
+	EnvCallbackURL = "EVE_CALLBACK_URL"
+	EnvUserAgent   = "EVE_USER_AGENT"
+)
+
+// NewFromEnv creates a new instance of the ESI struct with the client data read from
+// the EVE_CLIENT_ID, EVE_CLIENT_SECRET, and EVE_CALLBACK_URL environment variables,
+// and the user agent from EVE_USER_AGENT if it's set. If any of the required variables
+// are missing, the error lists all of them.
+func NewFromEnv() (ESI, error) {
+	var missing []string
+	values := make(map[string]string)
+	for _, name := range []string{EnvClientID, EnvSecret, EnvCallbackURL} {
+		values[name] = os.Getenv(name)
+		if values[name] == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return ESI{}, fmt.Errorf("Missing environment variables: %s", strings.Join(missing, ", "))
+	}
+	e := New(values[EnvClientID], values[EnvSecret], values[EnvCallbackURL])
+	if userAgent := os.Getenv(EnvUserAgent); userAgent != "" {
+		e.UserAgent = userAgent
 	}
+	return e, nil
 }
 
 // GetAuthorizeURL returns the URL that a user must visit in order to authenticate with the SSO
 func (e *ESI) GetAuthorizeURL() (string, error) {
-	log.Debug("Creating authorization url")
-	if e.ClientID == "" || e.ClientSecret == "" || e.ClientCallbackURL == "" {
-		es := "Missing client data - cannot generate callback URL"
-		log.Error(es)
-		return "", fmt.Errorf(es)
+	e.log.Debug("Creating authorization url")
+	if err := checkClientData(e); err != nil {
+		e.log.Error("Missing client data - cannot generate callback URL", "error", err)
+		return "", err
 	}
 	return fmt.Sprintf("%s?response_type=code&redirect_uri=%s&client_id=%s&scope=%s",
 		AuthorizeURL,
@@ -90,14 +258,53 @@ func createAuthorizationHeader(e *ESI) string {
 
 // Authenticate takes a code from the SSO and fetches the access token
 func (e *ESI) Authenticate(code string) error {
-	log.Debug("Starting authorization flow")
-	form := url.Values{
+	e.log.Debug("Starting authorization flow")
+	err := e.requestToken(url.Values{
 		"grant_type": []string{"authorization_code"},
 		"code":       []string{code},
+	})
+	if err != nil {
+		return err
+	}
+	e.CharacterID = 0
+	return nil
+}
+
+// RefreshAccessToken uses the refresh token to get a new access token from the SSO.
+// If the SSO rejects the refresh token, ErrRefreshTokenInvalid is returned; this
+// happens when the user has revoked access or changed their password, and the
+// token will never work again, so the user needs to authenticate again.
+func (e *ESI) RefreshAccessToken() error {
+	e.log.Debug("Refreshing access token")
+	if e.RefreshToken == "" {
+		return ErrMissingRefreshToken
 	}
-	req, err := http.NewRequest("POST", TokenURL, bytes.NewBufferString(form.Encode()))
+	return e.requestToken(url.Values{
+		"grant_type":    []string{"refresh_token"},
+		"refresh_token": []string{e.RefreshToken},
+	})
+}
+
+// TokenTTL returns how long until the access token expires, which is negative if it
+// already has. It's 0 if the expiry isn't known, like for tokens not fetched by this struct.
+func (e *ESI) TokenTTL() time.Duration {
+	if e.TokenExpiry.IsZero() {
+		return 0
+	}
+	return e.TokenExpiry.Sub(e.now())
+}
+
+// ssoErrorResponse is the body of an error response from the SSO
+type ssoErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// requestToken posts the form to the SSO's token URL and stores the tokens from the response
+func (e *ESI) requestToken(form url.Values) error {
+	req, err := e.newRequest("POST", TokenURL, bytes.NewBufferString(form.Encode()))
 	if err != nil {
-		log.Error("Cannot create a new request stuct")
+		e.log.Error("Cannot create a new request struct", "url", TokenURL, "error", err)
 		return err
 	}
 	req.Header.Add("Authorization", createAuthorizationHeader(e))
@@ -107,120 +314,1087 @@ func (e *ESI) Authenticate(code string) error {
 
 	resp, err := e.client.Do(req)
 	if err != nil {
-		log.Error("Error making authorization url request")
-		return err
+		e.log.Error("Error making token request", "url", TokenURL, "error", err)
+		return &SSOError{URL: TokenURL, Err: err}
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.Error("Cannot read response body")
-		return err
+		e.log.Error("Cannot read response body", "url", TokenURL, "error", err)
+		return &SSOError{URL: TokenURL, Err: err}
 	}
 	defer resp.Body.Close()
-	if string(body) == "" || resp.StatusCode != http.StatusOK {
-		log.Errorf("Error with authenticate response, code %d, body: '%s'", resp.StatusCode, body)
+	if resp.StatusCode != http.StatusOK {
+		e.log.Error("Error with token response", "url", TokenURL, "status", resp.StatusCode, "body", string(body))
+		ssoErr := newSSOError(TokenURL, resp, body)
+		if resp.StatusCode == http.StatusBadRequest && ssoErr.Code == "invalid_grant" && form.Get("grant_type") == "refresh_token" {
+			ssoErr.Err = ErrRefreshTokenInvalid
+		}
+		return ssoErr
+	}
+	if string(body) == "" {
+		e.log.Error("Empty token response", "url", TokenURL, "status", resp.StatusCode)
 		return fmt.Errorf("Response body is empty")
 	}
 	var respData authenticateResponse
 	err = json.Unmarshal(body, &respData)
 	if err != nil {
-		log.Errorf("Error parsing response, body: '%s'", body)
+		e.log.Error("Error parsing token response", "url", TokenURL, "body", string(body), "error", err)
 		return err
 	}
 
+	e.scopesAdded, e.scopesRemoved = nil, nil
+	if form.Get("grant_type") == "refresh_token" {
+		e.scopesAdded, e.scopesRemoved = scopeChanges(e.AccessToken, respData.AccessToken)
+		if len(e.scopesRemoved) > 0 {
+			e.log.Warn("Refreshed access token lost scopes", "removed", strings.Join(e.scopesRemoved, " "))
+		}
+	}
 	e.AccessToken = respData.AccessToken
-	e.RefreshToken = respData.RefreshToken
+	e.TokenExpiry = time.Time{}
+	if respData.ExpiresIn > 0 {
+		e.TokenExpiry = e.now().Add(time.Duration(respData.ExpiresIn) * time.Second)
+	}
+	if respData.RefreshToken != "" {
+		e.RefreshToken = respData.RefreshToken
+	}
 	return nil
 }
 
-// setupHeaders adds the standard headers to the request
+// newRequest creates a request that is cancelled when Shutdown is called
+func (e *ESI) newRequest(method, url string, body io.Reader) (*http.Request, error) {
+	e.warnDefaultUserAgent()
+	ctx := e.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return http.NewRequestWithContext(ctx, method, url, body)
+}
+
+// warnDefaultUserAgent logs a warning, once, if UserAgent hasn't been changed from the default
+func (e *ESI) warnDefaultUserAgent() {
+	if e.QuietUserAgent || e.UserAgent != DefaultUserAgent || e.userAgentWarning == nil {
+		return
+	}
+	e.userAgentWarning.Do(func() {
+		e.log.Warn("UserAgent is the default; set it to describe your app and how to contact you, as CCP may throttle generic user agents",
+			"userAgent", e.UserAgent)
+	})
+}
+
+// Shutdown cancels all in-flight calls, which return with context.Canceled.
+// Calls made after Shutdown fail in the same way, so only call this when
+// the ESI struct is no longer needed, like when the process is stopping.
+func (e *ESI) Shutdown() {
+	e.log.Debug("Shutting down; cancelling in-flight calls")
+	if e.cancel != nil {
+		e.cancel()
+	}
+}
+
+// setupHeaders adds the standard headers to the request, including the access token if there is one
 func setupHeaders(e *ESI, req *http.Request) {
-	req.Header.Add("User-Agent", e.UserAgent)
-	req.Header.Add("Accept", "application/json")
+	setupPublicHeaders(e, req)
 	if e.AccessToken != "" {
 		req.Header.Add("Authorization", "Bearer "+e.AccessToken)
+		if e.UserAgentCharacter {
+			if claims, ok := unverifiedClaims(e.AccessToken); ok && claims.CharacterID != 0 {
+				req.Header.Set("User-Agent", fmt.Sprintf("%s (character: %d)", e.UserAgent, claims.CharacterID))
+			}
+		}
+	}
+}
+
+// setupPublicHeaders adds the standard headers to the request, without the access token
+func setupPublicHeaders(e *ESI, req *http.Request) {
+	req.Header.Add("User-Agent", e.UserAgent)
+	req.Header.Add("Accept", "application/json")
+	if e.compatibilityDate != "" {
+		req.Header.Set(CompatibilityDateHeader, e.compatibilityDate)
+	}
+}
+
+// CompatibilityDateHeader is the request header that tells ESI which date's version of
+// its routes to use
+const CompatibilityDateHeader = "X-Compatibility-Date"
+
+// SetCompatibilityDate sets the date, like "2020-01-01", whose version of ESI's routes
+// every call asks for with the X-Compatibility-Date header, so that changes ESI makes
+// after it don't change the responses the app gets. The date must be a valid date in
+// that form and not be in the future, otherwise an error is returned and the date isn't
+// changed. An empty date stops the header from being sent.
+func (e *ESI) SetCompatibilityDate(date string) error {
+	if date != "" {
+		parsed, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return fmt.Errorf("Invalid compatibility date '%s': expected a date like 2020-01-01", date)
+		}
+		if parsed.After(e.now().UTC()) {
+			return fmt.Errorf("Invalid compatibility date '%s': it's in the future", date)
+		}
+	}
+	e.compatibilityDate = date
+	return nil
+}
+
+// CompatibilityDate returns the date set with SetCompatibilityDate, or "" if it isn't set
+func (e *ESI) CompatibilityDate() string {
+	return e.compatibilityDate
+}
+
+// do sends a request to ESI, retrying it if it's rate limited, or
+// after refreshing the access token if it's forbidden and AutoRefresh is set
+func (e *ESI) do(req *http.Request) (*http.Response, error) {
+	resp, err := e.doRateLimited(req)
+	if err != nil || resp.StatusCode != http.StatusForbidden || !e.AutoRefresh || e.RefreshToken == "" {
+		return resp, err
+	}
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == req.Header.Get("Authorization") {
+		return resp, err
+	}
+	retry, ok := retryRequest(req)
+	if !ok {
+		return resp, err
+	}
+	resp.Body.Close()
+	e.log.Info("Call was forbidden; refreshing the access token and retrying", "method", req.Method, "url", req.URL.String())
+	if err := e.refreshAfterForbidden(token); err != nil {
+		return nil, err
+	}
+	retry.Header.Set("Authorization", "Bearer "+e.AccessToken)
+	return e.doRateLimited(retry)
+}
+
+// refreshAfterForbidden refreshes the access token after a call with the token was
+// forbidden, unless another call has refreshed it in the meantime
+func (e *ESI) refreshAfterForbidden(token string) error {
+	if e.tokenMu != nil {
+		e.tokenMu.Lock()
+		defer e.tokenMu.Unlock()
+	}
+	if e.AccessToken != token {
+		return nil
+	}
+	return e.RefreshAccessToken()
+}
+
+// doRateLimited sends a request to ESI, retrying it if it's rate limited
+func (e *ESI) doRateLimited(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := e.doOnce(req)
+		if err != nil || !isRateLimitStatus(resp.StatusCode) || attempt >= e.MaxRetries {
+			return resp, err
+		}
+		wait := retryAfter(resp.Header)
+		if wait > maxRetryWait {
+			return resp, err
+		}
+		// context deadlines are in real time, whatever the struct's clock says
+		if deadline, ok := req.Context().Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return resp, err
+		}
+		retry, ok := retryRequest(req)
+		if !ok {
+			return resp, err
+		}
+		resp.Body.Close()
+		e.log.Warn("Rate limited by ESI; retrying", "method", req.Method, "url", req.URL.String(), "status", resp.StatusCode, "wait", wait, "attempt", attempt+1)
+		if err := e.sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+		req = retry
+	}
+}
+
+// doOnce sends a request to ESI, going through the circuit breaker, and logs the call.
+// A call that runs out of its route's timeout counts as timed out for the circuit breaker.
+func (e *ESI) doOnce(req *http.Request) (*http.Response, error) {
+	if e.breakers == nil || e.BreakerThreshold <= 0 {
+		return e.doWithTimeout(req)
+	}
+	breaker := e.breakers.get(req.URL.Host)
+	probe, err := breaker.allow(e.BreakerCooldown, e.now())
+	if err != nil {
+		e.log.Warn("Not making call to ESI", "method", req.Method, "url", req.URL.String(), "error", err)
+		return nil, err
+	}
+	if probe {
+		e.log.Debug("Circuit breaker cooldown has passed; letting a request through", "url", req.URL.String())
+	}
+	resp, err := e.doWithTimeout(req)
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		// the caller gave up on the call, which says nothing about whether ESI is up
+		breaker.release()
+	case isBreakerFailure(resp, err):
+		if failures, opened := breaker.failure(e.BreakerThreshold, e.now()); opened {
+			e.log.Warn("Consecutive ESI failures; opening circuit breaker", "failures", failures)
+		}
+	case err != nil:
+		breaker.release()
+	default:
+		if breaker.success() {
+			e.log.Info("ESI has recovered; closing circuit breaker")
+		}
+	}
+	return resp, err
+}
+
+// doWithTimeout sends a request through logCall, with the timeout from RouteTimeouts for its
+// route in place of the HTTP client's timeout, if there is one
+func (e *ESI) doWithTimeout(req *http.Request) (*http.Response, error) {
+	timeout, ok := matchRoutePrefix(e.RouteTimeouts, routeOf(req.URL.String()))
+	if !ok || timeout <= 0 {
+		return e.logCall(req, e.client)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	client := *e.client
+	client.Timeout = 0
+	resp, err := e.logCall(req.WithContext(ctx), &client)
+	if err != nil {
+		cancel()
+		return resp, err
 	}
+	// the timeout covers reading the body, as the client's does
+	resp.Body = closeFunc{resp.Body, cancel}
+	return resp, nil
+}
+
+// logCall sends a request with the HTTP client and logs its method, URL, status, and duration
+func (e *ESI) logCall(req *http.Request, client *http.Client) (*http.Response, error) {
+	start := e.now()
+	resp, err := client.Do(req)
+	duration := e.now().Sub(start)
+	if e.CollectRouteMetrics && e.metrics != nil {
+		e.metrics.record(metricsRoute(req.URL.String()), duration, err != nil || resp.StatusCode >= 400)
+	}
+	if err != nil {
+		e.log.Error("Error making request to ESI", "method", req.Method, "url", req.URL.String(), "duration", duration, "error", err)
+		return resp, err
+	}
+	e.errorLimit.update(resp.Header, e.now())
+	e.log.Info("Made call to ESI", "method", req.Method, "url", req.URL.String(), "status", resp.StatusCode, "duration", duration, "cacheHit", false)
+	return resp, err
 }
 
 // WhoAmI returns basic information about the access token's character
 func (e *ESI) WhoAmI() (*gabs.Container, error) {
-	log.Info("Making whoami request")
-	req, err := http.NewRequest("GET", VerifyURL, nil)
+	e.log.Info("Making whoami request", "url", VerifyURL)
+	req, err := e.newRequest("GET", VerifyURL, nil)
 	if err != nil {
 		return nil, err
 	}
 	setupHeaders(e, req)
 	resp, err := e.client.Do(req)
 	if err != nil {
-		log.Error("Error making whoami request to ESI")
-		return nil, err
+		e.log.Error("Error making whoami request to the SSO", "url", VerifyURL, "error", err)
+		return nil, &SSOError{URL: VerifyURL, Err: err}
 	}
 	defer resp.Body.Close()
-	json, err := gabs.ParseJSONBuffer(resp.Body)
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		e.log.Error("Cannot read response body", "url", VerifyURL, "error", err)
+		return nil, &SSOError{URL: VerifyURL, Err: err}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		e.log.Error("Error with whoami response", "url", VerifyURL, "status", resp.StatusCode, "body", string(body))
+		return nil, newSSOError(VerifyURL, resp, body)
+	}
+	json, err := parseJSON(resp, body)
 	if err != nil {
-		log.Error("Error converting response body to Gabs container")
+		e.log.Error("Error converting response body to Gabs container", "url", VerifyURL, "error", err)
 		return nil, err
 	}
 	return json, nil
 }
 
-// Get fetches data from ESI (or returns cached data)
+// characterID returns the ID of the access token's character, asking the SSO if it isn't known yet
+func (e *ESI) characterID() (int32, error) {
+	if e.CharacterID != 0 {
+		return e.CharacterID, nil
+	}
+	data, err := e.WhoAmI()
+	if err != nil {
+		return 0, err
+	}
+	id, ok := data.Path("CharacterID").Data().(float64)
+	if !ok {
+		e.log.Error("No character ID in whoami response", "body", data.String())
+		return 0, fmt.Errorf("No character ID in whoami response")
+	}
+	e.CharacterID = int32(id)
+	return e.CharacterID, nil
+}
+
+// buildURL returns the full ESI URL for the path
+func (e *ESI) buildURL(path string) string {
+	return buildVersionURL(e.Version, path)
+}
+
+// buildVersionURL returns the full ESI URL for the path in the version.
+// A query string in the path is kept after the trailing slash, with its params in
+// sorted order, so that the same params in any order make the same URL and cache key.
+func buildVersionURL(version, path string) string {
+	return buildBaseURL(BaseURL, version, path)
+}
+
+// buildBaseURL is like buildVersionURL, but for ESI at the base URL
+func buildBaseURL(base, version, path string) string {
+	path, query := splitQuery(NormalizePath(path))
+	u := strings.TrimSuffix(base, "/") + "/" + version + "/" + path + "/"
+	if query != "" {
+		u += "?" + query
+	}
+	return u
+}
+
+// NormalizePath cleans up a path to pass to Get and the other methods: it trims spaces,
+// strips leading and trailing slashes, and drops empty segments, so that "/characters/123/",
+// " characters/123 ", and "characters//123" all become "characters/123". A query string
+// in the path is kept as it is. The methods normalize the paths they're given with it.
+func NormalizePath(path string) string {
+	path = strings.TrimSpace(path)
+	query := ""
+	if i := strings.Index(path, "?"); i != -1 {
+		path, query = path[:i], path[i:]
+	}
+	segments := strings.Split(path, "/")
+	kept := segments[:0]
+	for _, segment := range segments {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			kept = append(kept, segment)
+		}
+	}
+	return strings.Join(kept, "/") + query
+}
+
+// splitQuery splits a path into the path and its query string, with the query's params
+// in sorted order. If the query string can't be parsed, it's returned as-is.
+func splitQuery(path string) (string, string) {
+	i := strings.Index(path, "?")
+	if i == -1 {
+		return path, ""
+	}
+	path, query := path[:i], path[i+1:]
+	if values, err := url.ParseQuery(query); err == nil {
+		query = values.Encode()
+	}
+	return strings.TrimSuffix(path, "/"), query
+}
+
+// Get fetches data from ESI (or returns cached data).
+// A successful response is returned as-is, even if it's empty, like the empty array
+// for a character with no contracts; that's not an error. If ESI responds with a status
+// code other than 2xx, no data is returned along with an *ESIError, even when the error
+// body is valid JSON.
+//
+// If ServeStaleOnError is set and the call fails, the expired cached data is returned
+// instead, if there is any, along with a *StaleError.
+//
+// RequestOptions, like NoCache, can be passed after the format args to change how
+// the call is made.
 func (e *ESI) Get(path string, args ...interface{}) (*gabs.Container, error) {
-	url := BaseURL + e.Version + "/" + fmt.Sprintf(path, args...) + "/"
-	cached := e.cache.get(url)
-	if cached != nil {
-		log.Info("Returning cached value for URL '%s'", url)
-		return cached, nil
+	url, opts := e.optionsURL(path, args)
+	opts, cancel := opts.withDeadline()
+	defer cancel()
+	json, _, err := e.getWith(url, opts)
+	if err != nil && e.ServeStaleOnError && !opts.noCache && isFailover(err) && !e.callerDone(opts) {
+		key := e.cacheKey("GET", url)
+		if stale := e.cache.stale(key); stale != nil {
+			expires, _ := e.cache.expires(key)
+			e.log.Warn("Returning expired cached value after an error", "method", "GET", "url", url, "expires", expires, "error", err)
+			return stale, &StaleError{URL: url, Expires: expires, Err: err}
+		}
+	}
+	return json, err
+}
+
+// GetWithParams is like Get, but adds the params to the URL as its query string.
+// The params are encoded, so they don't have to be escaped, and are put in sorted order.
+func (e *ESI) GetWithParams(path string, params url.Values, args ...interface{}) (*gabs.Container, error) {
+	return e.Get(path, append(args, WithParams(params))...)
+}
+
+// GetPublic is like Get, but never sends the access token, even if one is set.
+// Use this for public routes on an authenticated struct, so that the calls aren't
+// tied to the character. Public and authenticated calls to a URL share the cache.
+func (e *ESI) GetPublic(path string, args ...interface{}) (*gabs.Container, error) {
+	return e.Get(path, append(args, RequestOption(func(o *requestOptions) {
+		o.public = true
+	}))...)
+}
+
+// get returns the cached data and response headers for the URL, or fetches them from ESI
+func (e *ESI) get(url string) (*gabs.Container, http.Header, error) {
+	return e.getWith(url, requestOptions{})
+}
+
+// getWith is like get, but with the options for the call
+func (e *ESI) getWith(url string, opts requestOptions) (*gabs.Container, http.Header, error) {
+	json, header, _, err := e.getCached(url, opts)
+	return json, header, err
+}
+
+// getCached is like getWith, but also returns whether the response came from the cache
+// without a call to ESI
+func (e *ESI) getCached(url string, opts requestOptions) (*gabs.Container, http.Header, bool, error) {
+	if !opts.noCache {
+		key := e.cacheKey("GET", url)
+		if e.CacheNotFound {
+			if err, ok := e.cache.notFound(key); ok {
+				e.log.Info("Returning cached not found response", "method", "GET", "url", url, "cacheHit", true)
+				e.cacheEvent(CacheHit, "GET", url, time.Time{}, 0)
+				return nil, nil, true, err
+			}
+		}
+		cached, ok := e.cache.getEntry(key)
+		if ok {
+			e.log.Info("Returning cached value", "method", "GET", "url", url, "cacheHit", true)
+			e.cacheEvent(CacheHit, "GET", url, cached.Expires, 0)
+			return cached.Data, cached.Header, true, nil
+		}
+		e.cacheMissEvent("GET", url, key)
 	}
-	log.Info("Making GET call to URL '%s'\n", url)
-	req, err := http.NewRequest("GET", url, nil)
+	json, header, err := e.fetch(url, opts)
+	return json, header, false, err
+}
+
+// GetWithETag is like Get, but also returns the response's ETag, to keep in your own
+// storage alongside the data, and whether the response came straight from the cache
+// without a call to ESI. A response that ESI said was unchanged since the cached one,
+// so that the cached data was reused, didn't come straight from the cache. The ETag is
+// "" if ESI didn't send one.
+func (e *ESI) GetWithETag(path string, args ...interface{}) (*gabs.Container, string, bool, error) {
+	url, opts := e.optionsURL(path, args)
+	opts, cancel := opts.withDeadline()
+	defer cancel()
+	json, header, fromCache, err := e.getCached(url, opts)
 	if err != nil {
-		log.Error("Error creating a new request struct")
-		return nil, err
+		return nil, "", fromCache, err
+	}
+	return json, header.Get("ETag"), fromCache, nil
+}
+
+// GetWithVersionFallback is like Get, but tries each of the ESI versions (like "latest",
+// "v2", "v1") in order until one of them responds successfully. Use this to keep working
+// when a route is broken in one version but not in an older one. If every version fails,
+// the error from the last one is returned. RequestOptions can be passed after the
+// format args, as with Get; a deadline is shared between all of the versions.
+func (e *ESI) GetWithVersionFallback(path string, versions []string, args ...interface{}) (*gabs.Container, error) {
+	args, opts := splitOptions(args)
+	opts, cancel := opts.withDeadline()
+	defer cancel()
+	path = withQuery(fmt.Sprintf(path, args...), opts.params)
+	err := fmt.Errorf("No versions given for path '%s'", path)
+	for _, version := range versions {
+		var json *gabs.Container
+		json, _, err = e.getWith(buildVersionURL(version, path), opts)
+		if err == nil {
+			return json, nil
+		}
+		e.log.Warn("Error getting path from version", "path", path, "version", version, "error", err)
+	}
+	return nil, err
+}
+
+// GetWithFailover is like Get, but tries each of the base URLs in BaseURLs in order,
+// moving on to the next when a call can't be made, times out, or ESI responds with a 5xx
+// status code.
+// Any other error, like a 404, is returned straight away, as the other base URLs would
+// respond the same. If every base URL fails, the error from the last one is returned.
+// Responses are cached per base URL. RequestOptions can be passed after the format args,
+// as with Get; a deadline is shared between all of the base URLs.
+func (e *ESI) GetWithFailover(path string, args ...interface{}) (*gabs.Container, error) {
+	args, opts := splitOptions(args)
+	opts, cancel := opts.withDeadline()
+	defer cancel()
+	path = withQuery(fmt.Sprintf(path, args...), opts.params)
+	bases := e.BaseURLs
+	if len(bases) == 0 {
+		bases = []string{BaseURL}
+	}
+	var err error
+	for _, base := range bases {
+		var json *gabs.Container
+		json, _, err = e.getWith(buildBaseURL(base, e.Version, path), opts)
+		if err == nil || !isFailover(err) || e.callerDone(opts) {
+			return json, err
+		}
+		e.log.Warn("Error getting path from base URL", "path", path, "baseURL", base, "error", err)
+	}
+	return nil, err
+}
+
+// callerDone returns whether the call's own context, or the struct's after Shutdown, has
+// ended, as opposed to a call timing out on the HTTP client's Timeout or a RouteTimeouts one
+func (e *ESI) callerDone(opts requestOptions) bool {
+	return (opts.ctx != nil && opts.ctx.Err() != nil) || (e.ctx != nil && e.ctx.Err() != nil)
+}
+
+// isFailover returns whether GetWithFailover should try the next base URL after the error,
+// and whether Get should serve expired data after it when ServeStaleOnError is set. Calls
+// that timed out count, so check callerDone as well for calls the caller gave up on. A
+// response that was too large isn't an outage, so doesn't count.
+func isFailover(err error) bool {
+	var tooLarge *ResponseTooLargeError
+	if errors.As(err, &tooLarge) {
+		return false
+	}
+	var esiErr *ESIError
+	return !errors.As(err, &esiErr) || esiErr.StatusCode >= 500
+}
+
+// GetStaleOK returns the cached data for the path straight away, even if it has expired.
+// If the cached data has expired, it is refreshed in the background so that later
+// calls get fresh data; only one refresh runs per URL at a time. If nothing is cached
+// for the path, this fetches it like Get.
+//
+// RequestOptions can be passed after the format args, as with Get. With NoCache, this
+// is the same as Get. The context and deadline only apply to a fetch made straight away,
+// not to a refresh in the background, which carries on after this returns.
+func (e *ESI) GetStaleOK(path string, args ...interface{}) (*gabs.Container, error) {
+	url, opts := e.optionsURL(path, args)
+	if opts.noCache {
+		return e.Get(path, args...)
+	}
+	key := e.cacheKey("GET", url)
+	cached, ok := e.cache.getEntry(key)
+	if ok {
+		e.log.Info("Returning cached value", "method", "GET", "url", url, "cacheHit", true)
+		e.cacheEvent(CacheHit, "GET", url, cached.Expires, 0)
+		return cached.Data, nil
+	}
+	e.cacheMissEvent("GET", url, key)
+	stale := e.cache.stale(key)
+	if stale == nil {
+		opts, cancel := opts.withDeadline()
+		defer cancel()
+		json, _, err := e.fetch(url, opts)
+		return json, err
+	}
+	if e.refreshing.start(key) {
+		e.log.Debug("Refreshing expired data in the background", "url", url)
+		background := opts
+		background.ctx, background.deadline = nil, time.Time{}
+		go func() {
+			defer e.refreshing.done(key)
+			if _, _, err := e.fetch(url, background); err != nil {
+				e.log.Warn("Error refreshing expired data", "url", url, "error", err)
+			}
+		}()
+	}
+	e.log.Info("Returning expired cached value", "method", "GET", "url", url, "cacheHit", true, "stale", true)
+	return stale, nil
+}
+
+// GetIfNoneMatch makes a GET call to ESI conditional on the etag, without using the cache
+// at all, for apps that keep responses in their own storage. If ESI responds that the
+// data hasn't changed since the etag, no data is returned and the bool is true.
+// Otherwise the response is returned with its ETag, to pass in next time.
+// An empty etag makes the call unconditional. RequestOptions can be passed after the
+// format args, as with Get.
+func (e *ESI) GetIfNoneMatch(etag, path string, args ...interface{}) (*gabs.Container, string, bool, error) {
+	url, opts := e.optionsURL(path, args)
+	opts, cancel := opts.withDeadline()
+	defer cancel()
+	req, err := e.newRequest("GET", url, nil)
+	if err != nil {
+		e.log.Error("Error creating a new request struct", "method", "GET", "url", url, "error", err)
+		return nil, "", false, err
 	}
 	setupHeaders(e, req)
-	resp, err := e.client.Do(req)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	req, done := opts.apply(req)
+	defer done()
+	resp, err := e.do(req)
 	if err != nil {
-		log.Error("Error making request to ESI")
-		return nil, err
+		return nil, "", false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		newETag := resp.Header.Get("ETag")
+		if newETag == "" {
+			newETag = etag
+		}
+		return nil, newETag, true, nil
+	}
+	json, err := readResponse(url, resp, e.MaxResponseBytes)
+	if err != nil {
+		e.log.Error("Error with response from ESI", "method", "GET", "url", url, "status", resp.StatusCode, "error", err)
+		return nil, "", false, err
+	}
+	return json, resp.Header.Get("ETag"), false, nil
+}
+
+// fetch makes a GET call to ESI for the URL and caches the response.
+// If there's an expired entry in the cache with an ETag, the call is made
+// conditional on it, and the cached data is reused if ESI says it hasn't changed.
+// The options can make the call public, skip the ETag, or set its context and headers.
+func (e *ESI) fetch(url string, opts requestOptions) (*gabs.Container, http.Header, error) {
+	req, err := e.newRequest("GET", url, nil)
+	if err != nil {
+		e.log.Error("Error creating a new request struct", "method", "GET", "url", url, "error", err)
+		return nil, nil, err
+	}
+	if opts.public {
+		setupPublicHeaders(e, req)
+	} else {
+		setupHeaders(e, req)
+	}
+	key := e.cacheKey("GET", url)
+	var etag string
+	if !opts.noCache {
+		etag = e.cache.etag(key)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	req, done := opts.apply(req)
+	defer done()
+	start := e.now()
+	resp, err := e.do(req)
+	if err != nil {
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
-	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if resp.StatusCode == http.StatusNotModified && etag != "" {
+		if entry, ok := e.cache.revalidate(key, resp.Header, e.cacheTTL(url)); ok {
+			e.log.Info("Data is unchanged; reusing cached value", "method", "GET", "url", url, "status", resp.StatusCode, "cacheHit", true)
+			e.cacheEvent(CacheStore, "GET", url, entry.Expires, e.now().Sub(start))
+			return entry.Data, entry.Header, nil
+		}
+		// the entry was dropped while the request was in flight
+		e.cache.remove(key)
+		return e.fetch(url, opts)
+	}
+	json, err := readResponse(url, resp, e.MaxResponseBytes)
+	if err != nil {
+		e.log.Error("Error with response from ESI", "method", "GET", "url", url, "status", resp.StatusCode, "error", err)
+		var esiErr *ESIError
+		if e.CacheNotFound && errors.As(err, &esiErr) && esiErr.StatusCode == http.StatusNotFound {
+			ttl := e.NotFoundTTL
+			if ttl <= 0 {
+				ttl = defaultNotFoundTTL
+			}
+			e.cache.setNotFound(key, esiErr, ttl)
+		}
+		return nil, nil, err
+	}
+	e.cache.countMiss()
+	if e.cache.set(key, json, resp.Header, e.cacheTTL(url)) == nil {
+		e.cacheStoreEvent("GET", url, key, e.now().Sub(start))
+	}
+	return json, resp.Header, nil
+}
+
+// postCacheKey returns the cache key for a POST request, made from the URL and a hash of the body
+func postCacheKey(url, data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return url + "#" + hex.EncodeToString(sum[:])
+}
+
+// Post sends data to ESI and returns the response.
+// If CachePOST is set, responses are cached (and returned from the cache) per URL and body.
+func (e *ESI) Post(path, data string) (*gabs.Container, error) {
+	return e.post(path, data, false)
+}
+
+// post is Post, with the body gzipped if compress is set
+func (e *ESI) post(path, data string, compress bool) (*gabs.Container, error) {
+	url := e.buildURL(path)
+	var key string
+	if e.CachePOST {
+		key = postCacheKey(e.cacheKey("POST", url), data)
+		cached, ok := e.cache.getEntry(key)
+		if ok {
+			e.log.Info("Returning cached value", "method", "POST", "url", url, "cacheHit", true)
+			e.cacheEvent(CacheHit, "POST", url, cached.Expires, 0)
+			return cached.Data, nil
+		}
+		e.cacheMissEvent("POST", url, key)
+	}
+	start := e.now()
+	json, header, err := e.send("POST", path, data, compress)
 	if err != nil {
-		log.Error("Error converting response body to Gabs container")
 		return nil, err
 	}
-	e.cache.set(url, json, resp.Header)
+	if e.CachePOST && e.cache.set(key, json, header, e.cacheTTL(url)) == nil {
+		e.cacheStoreEvent("POST", url, key, e.now().Sub(start))
+	}
 	return json, nil
 }
 
-// Post sends data to ESI and returns the response
-func (e *ESI) Post(path, data string) (*gabs.Container, error) {
-	url := BaseURL + e.Version + "/" + path + "/"
-	log.Info("Making POST call to URL '%s'\n", url)
-	req, err := http.NewRequest("POST", url, strings.NewReader(data))
+// PostJSON is like Post, but encodes v as JSON for the request body. Pass Compressed
+// to gzip the body, for large ones like a thousand IDs to universe/names; it's the only
+// RequestOption PostJSON takes.
+func (e *ESI) PostJSON(path string, v interface{}, opts ...RequestOption) (*gabs.Container, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return e.post(path, string(body), newRequestOptions(opts).compress)
+}
+
+// PutJSON is like Put, but encodes v as JSON for the request body
+func (e *ESI) PutJSON(path string, v interface{}) (*gabs.Container, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return e.Put(path, string(body))
+}
+
+// Put sends data to ESI with a PUT request and returns the response
+func (e *ESI) Put(path, data string) (*gabs.Container, error) {
+	json, _, err := e.send("PUT", path, data, false)
+	return json, err
+}
+
+// Delete sends a DELETE request to ESI and returns the response
+func (e *ESI) Delete(path string) (*gabs.Container, error) {
+	json, _, err := e.send("DELETE", path, "", false)
+	return json, err
+}
+
+// send makes a call to ESI that sends data (POST, PUT, DELETE) and returns the response and its headers.
+// If compress is set, the data is gzipped, and sent again uncompressed if the route rejects that.
+// A successful call drops any cached responses returned by InvalidateOnWrite.
+func (e *ESI) send(method, path, data string, compress bool) (*gabs.Container, http.Header, error) {
+	url := e.buildURL(path)
+	compress = compress && data != ""
+	resp, err := e.sendRequest(method, url, data, compress)
+	if err != nil {
+		return nil, nil, err
+	}
+	if compress && resp.StatusCode == http.StatusUnsupportedMediaType {
+		resp.Body.Close()
+		e.log.Info("Compressed body rejected; sending it uncompressed", "method", method, "url", url)
+		resp, err = e.sendRequest(method, url, data, false)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	defer resp.Body.Close()
+	json, err := readResponse(url, resp, e.MaxResponseBytes)
+	if err != nil {
+		e.log.Error("Error with response from ESI", "method", method, "url", url, "status", resp.StatusCode, "error", err)
+		return nil, nil, err
+	}
+	e.invalidate(method, path)
+	return json, resp.Header, nil
+}
+
+// sendRequest sends the data to the URL, gzipped if compress is set
+func (e *ESI) sendRequest(method, url, data string, compress bool) (*http.Response, error) {
+	var body io.Reader
+	if data != "" {
+		body = strings.NewReader(data)
+	}
+	if compress {
+		compressed, err := gzipBody(data)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(compressed)
+	}
+	req, err := e.newRequest(method, url, body)
 	if err != nil {
-		log.Error("Error creating a new request struct")
+		e.log.Error("Error creating a new request struct", "method", method, "url", url, "error", err)
 		return nil, err
 	}
 	setupHeaders(e, req)
-	resp, err := e.client.Do(req)
+	if compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	return e.do(req)
+}
+
+// gzipBody compresses a request body with gzip
+func gzipBody(data string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(data)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Do makes a call to ESI with the standard headers and returns the response as-is,
+// without reading it, checking its status, or caching it. The caller must close the
+// response body. Use this when the other methods don't give enough control.
+//
+// RequestOptions can be passed after the format args, as with Get. The context and
+// deadline carry on applying while the body is read, until it's closed.
+func (e *ESI) Do(method, path string, body io.Reader, args ...interface{}) (*http.Response, error) {
+	url, opts := e.optionsURL(path, args)
+	req, err := e.newRequest(method, url, body)
+	if err != nil {
+		e.log.Error("Error creating a new request struct", "method", method, "url", url, "error", err)
+		return nil, err
+	}
+	setupHeaders(e, req)
+	opts, cancel := opts.withDeadline()
+	req, done := opts.apply(req)
+	resp, err := e.do(req)
+	if err != nil {
+		done()
+		cancel()
+		return nil, err
+	}
+	resp.Body = closeFunc{resp.Body, func() {
+		done()
+		cancel()
+	}}
+	return resp, nil
+}
+
+// GetRaw makes a GET call to ESI and returns the response body as-is, without parsing it
+// as JSON or caching it, for responses in other content types asked for with WithAccept.
+// If ESI responds with a status code other than 2xx, an *ESIError is returned.
+// RequestOptions can be passed after the format args, as with Get.
+func (e *ESI) GetRaw(path string, args ...interface{}) ([]byte, error) {
+	url, _ := e.optionsURL(path, args)
+	resp, err := e.Do("GET", path, nil, args...)
 	if err != nil {
-		log.Error("Error making request to ESI")
 		return nil, err
 	}
 	defer resp.Body.Close()
-	json, err := gabs.ParseJSONBuffer(resp.Body)
+	body, err := readBody(url, resp, e.MaxResponseBytes)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := newESIError(url, resp, body)
+		e.log.Error("Error with response from ESI", "method", "GET", "url", url, "status", resp.StatusCode, "error", err)
+		return nil, err
+	}
+	return body, nil
+}
+
+// readResponse reads a response body into a Gabs container, returning an *ESIError
+// if the response's status code is not 2xx. Empty bodies, like those of 204 responses,
+// result in an empty container. Bodies longer than the limit, if it's above 0, return
+// a *ResponseTooLargeError.
+func readResponse(url string, resp *http.Response, limit int64) (*gabs.Container, error) {
+	body, err := readBody(url, resp, limit)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, newESIError(url, resp, body)
+	}
+	if contentType := resp.Header.Get("Content-Type"); len(body) > 0 && !isJSONContentType(contentType) {
+		return nil, &ContentTypeError{resp.StatusCode, contentType, url}
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return gabs.New(), nil
+	}
+	return parseJSON(resp, body)
+}
+
+// readBody reads a response body, returning a *ResponseTooLargeError without reading any
+// more of it than the limit, if it's above 0
+func readBody(url string, resp *http.Response, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return ioutil.ReadAll(resp.Body)
+	}
+	if resp.ContentLength > limit {
+		return nil, &ResponseTooLargeError{resp.StatusCode, limit, url}
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
 	if err != nil {
-		log.Error("Error converting response body to Gabs container")
 		return nil, err
 	}
+	if int64(len(body)) > limit {
+		return nil, &ResponseTooLargeError{resp.StatusCode, limit, url}
+	}
+	return body, nil
+}
+
+// isJSONContentType returns whether the Content-Type header value is JSON.
+// A missing content type is allowed, so that the body is still parsed.
+func isJSONContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// parseJSON parses a response body into a Gabs container, returning a *ParseError if it isn't JSON
+func parseJSON(resp *http.Response, body []byte) (*gabs.Container, error) {
+	json, err := gabs.ParseJSON(body)
+	if err != nil {
+		return nil, newParseError(resp, body, err)
+	}
 	return json, nil
 }
 
+// decode unmarshals the JSON in the container into v
+func decode(c *gabs.Container, v interface{}) error {
+	return json.Unmarshal(c.Bytes(), v)
+}
+
+// invalidate drops the cached responses for the paths that InvalidateOnWrite returns for the call
+func (e *ESI) invalidate(method, path string) {
+	if e.InvalidateOnWrite == nil {
+		return
+	}
+	for _, p := range e.InvalidateOnWrite(method, path) {
+		e.log.Debug("Invalidating cached response", "path", p, "method", method, "writePath", path)
+		e.cache.remove(e.cacheKey("GET", e.buildURL(p)))
+	}
+}
+
+// TimeUntilExpiry returns how long until the cached response for the path expires,
+// and whether there is an unexpired response for the path in the cache.
+// Polling loops can sleep for the returned duration instead of polling on a fixed interval.
+func (e *ESI) TimeUntilExpiry(path string, args ...interface{}) (time.Duration, bool) {
+	url, _ := e.optionsURL(path, args)
+	expires, ok := e.cache.expires(e.cacheKey("GET", url))
+	if !ok {
+		return 0, false
+	}
+	remaining := expires.Sub(e.now())
+	if remaining <= 0 {
+		return 0, false
+	}
+	return remaining, true
+}
+
+// defaultCacheTTLOverrides returns the cache times for routes whose data rarely changes
+func defaultCacheTTLOverrides() map[string]time.Duration {
+	return map[string]time.Duration{
+		"universe/types": 24 * time.Hour,
+		// the map only changes with new expansions
+		"universe/regions":        24 * time.Hour,
+		"universe/constellations": 24 * time.Hour,
+		"universe/systems":        24 * time.Hour,
+		// factions and races only change with new expansions, if ever
+		"universe/factions": 24 * time.Hour,
+		"universe/races":    24 * time.Hour,
+		// dogma only changes with game updates
+		"dogma": 24 * time.Hour,
+		// killmails never change once they exist
+		"killmails": 365 * 24 * time.Hour,
+	}
+}
+
+// cacheKey returns the cache key for a call, from CacheKeyFunc if it's set
+func (e *ESI) cacheKey(method, url string) string {
+	if e.CacheKeyFunc == nil {
+		return url
+	}
+	return e.CacheKeyFunc(method, url)
+}
+
+// cacheTTL returns the CacheTTLOverrides value for the URL's route, or 0 if there isn't one
+func (e *ESI) cacheTTL(u string) time.Duration {
+	ttl, _ := matchRoutePrefix(e.CacheTTLOverrides, routeOf(u))
+	return ttl
+}
+
+// Cache returns the response cache, to configure its size and eviction handling
+func (e *ESI) Cache() *Cache {
+	return e.cache
+}
+
+// SetCache replaces the response cache, like with one from RestoreCache. The cache is
+// given the struct's clock and logger.
+func (e *ESI) SetCache(c *Cache) {
+	c.clock = e.clock
+	c.log = e.log
+	e.cache = c
+}
+
+// Stats returns counts of how GET calls have been served since the cache was created
+func (e *ESI) Stats() CacheStats {
+	return e.cache.Stats()
+}
+
+// Clone returns a copy of the ESI struct that can be changed (like setting a different
+// AccessToken) without affecting the original, for example to use per web request.
+// The copy shares the HTTP client and circuit breaker with the original, but starts
+// with an empty cache of its own, as cached responses to authenticated calls belong
+// to the token they were made with. The copy's CharacterID is cleared, so that it's
+// looked up again for the copy's token.
+func (e *ESI) Clone() *ESI {
+	clone := e.clone()
+	clone.ClearCache()
+	return clone
+}
+
+// CloneSharingCache is like Clone, but the copy shares the cache with the original.
+// Only use it for copies that keep the same AccessToken, or that make only public calls,
+// as otherwise one token's cached responses are returned to the other.
+func (e *ESI) CloneSharingCache() *ESI {
+	return e.clone()
+}
+
+// clone copies the struct for Clone and CloneSharingCache
+func (e *ESI) clone() *ESI {
+	clone := *e
+	clone.CharacterID = 0
+	if e.CacheTTLOverrides != nil {
+		clone.CacheTTLOverrides = make(map[string]time.Duration, len(e.CacheTTLOverrides))
+		for prefix, ttl := range e.CacheTTLOverrides {
+			clone.CacheTTLOverrides[prefix] = ttl
+		}
+	}
+	if e.RouteTimeouts != nil {
+		clone.RouteTimeouts = make(map[string]time.Duration, len(e.RouteTimeouts))
+		for prefix, timeout := range e.RouteTimeouts {
+			clone.RouteTimeouts[prefix] = timeout
+		}
+	}
+	return &clone
+}
+
+// HTTPClient returns the HTTP client used for calls to ESI and the SSO, to set a timeout,
+// a cookie jar, or anything else the other settings don't cover. The client is shared
+// with clones of the struct, so changes to it apply to them as well.
+func (e *ESI) HTTPClient() *http.Client {
+	return e.client
+}
+
+// SetTransport replaces the transport used by the HTTP client, for example
+// to stub out ESI in tests or to wrap requests with instrumentation.
+func (e *ESI) SetTransport(rt http.RoundTripper) {
+	e.client.Transport = rt
+}
+
 // ClearCache creates a new cache, overriding the previous
 func (e *ESI) ClearCache() {
-	log.Debug("Clearing cache")
-	cache := make(Cache)
-	e.cache = &cache
+	e.log.Debug("Clearing cache")
+	cache := newCache()
+	cache.clock = e.clock
+	cache.log = e.log
+	cache.MaxEntries = e.cache.MaxEntries
+	cache.OnEvict = e.cache.OnEvict
+	cache.ExpiryJitter = e.cache.ExpiryJitter
+	e.cache = cache
+}
+
+// Reset returns the struct to an unauthenticated state, like after a user logs out,
+// by clearing the tokens and the character. The client data, scopes, settings, and
+// HTTP client are kept. If clearCache is set, the cache is also cleared; do this when
+// another character will use the struct, as cached responses may belong to the last one.
+func (e *ESI) Reset(clearCache bool) {
+	e.log.Debug("Resetting to an unauthenticated state", "clearCache", clearCache)
+	e.AccessToken = ""
+	e.RefreshToken = ""
+	e.TokenExpiry = time.Time{}
+	e.CharacterID = 0
+	if clearCache {
+		e.ClearCache()
+	}
 }
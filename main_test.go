@@ -0,0 +1,1459 @@
+package goesi
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// roundTripFunc lets a function stand in for ESI
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// stubResponse builds a response with the status, body, and header key-value pairs
+func stubResponse(status int, body string, header ...string) *http.Response {
+	h := make(http.Header)
+	for i := 0; i+1 < len(header); i += 2 {
+		h.Set(header[i], header[i+1])
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     h,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
+// newStubbedESI returns an ESI struct whose requests are all answered by the function
+func newStubbedESI(f roundTripFunc) *ESI {
+	e := New("clientID", "clientSecret", "http://localhost/callback")
+	e.SetTransport(f)
+	return &e
+}
+
+func TestGetCaching(t *testing.T) {
+	expires := time.Now().UTC().Add(time.Hour).Format(http.TimeFormat)
+	tests := []struct {
+		name     string
+		header   []string
+		expected int
+	}{
+		{"cached until expiry", []string{"Expires", expires}, 1},
+		{"no expires header", nil, 2},
+	}
+	for _, test := range tests {
+		calls := 0
+		e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+			calls++
+			return stubResponse(200, `{"players": 30000}`, test.header...), nil
+		})
+		for i := 0; i < 2; i++ {
+			data, err := e.Get("status")
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %s", test.name, err)
+			}
+			if data.Path("players").Data().(float64) != 30000 {
+				t.Fatalf("%s: unexpected data: %s", test.name, data)
+			}
+		}
+		if calls != test.expected {
+			t.Fatalf("%s: expected %d requests, made %d", test.name, test.expected, calls)
+		}
+	}
+}
+
+func TestPostCaching(t *testing.T) {
+	expires := time.Now().UTC().Add(time.Hour).Format(http.TimeFormat)
+	for _, cachePOST := range []bool{false, true} {
+		calls := 0
+		e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+			calls++
+			return stubResponse(200, `[]`, "Expires", expires), nil
+		})
+		e.CachePOST = cachePOST
+		e.Post("universe/names", "[1]")
+		e.Post("universe/names", "[1]")
+		e.Post("universe/names", "[2]")
+		expected := 3
+		if cachePOST {
+			expected = 2
+		}
+		if calls != expected {
+			t.Fatalf("CachePOST %v: expected %d requests, made %d", cachePOST, expected, calls)
+		}
+	}
+}
+
+func TestInvalidateOnWrite(t *testing.T) {
+	expires := time.Now().UTC().Add(time.Hour).Format(http.TimeFormat)
+	gets := 0
+	writeStatus := 204
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if req.Method != "GET" {
+			return stubResponse(writeStatus, ""), nil
+		}
+		gets++
+		return stubResponse(200, `[{"label_id": 1}]`, "Expires", expires), nil
+	})
+	var writes []string
+	e.InvalidateOnWrite = func(method, path string) []string {
+		writes = append(writes, method+" "+path)
+		return []string{"characters/90000001/mail/labels"}
+	}
+	steps := []struct {
+		name  string
+		write func() error
+		gets  int
+	}{
+		{"cached", func() error { return nil }, 1},
+		{"put", func() error {
+			_, err := e.Put("characters/90000001/mail/labels/1", `{"name": "Fleet"}`)
+			return err
+		}, 2},
+		{"delete", func() error {
+			_, err := e.Delete("characters/90000001/mail/labels/1")
+			return err
+		}, 3},
+		{"failed delete", func() error {
+			writeStatus = 500
+			if _, err := e.Delete("characters/90000001/mail/labels/1"); err == nil {
+				t.Fatal("Expected the failed delete to return an error")
+			}
+			return nil
+		}, 3},
+	}
+	for _, step := range steps {
+		if err := step.write(); err != nil {
+			t.Fatalf("%s: unexpected error %v", step.name, err)
+		}
+		if _, err := e.Get("characters/%d/mail/labels", 90000001); err != nil {
+			t.Fatalf("%s: unexpected error %v", step.name, err)
+		}
+		if gets != step.gets {
+			t.Fatalf("%s: expected %d GET requests, made %d", step.name, step.gets, gets)
+		}
+	}
+	if len(writes) != 2 || writes[0] != "PUT characters/90000001/mail/labels/1" || writes[1] != "DELETE characters/90000001/mail/labels/1" {
+		t.Fatalf("Expected InvalidateOnWrite to be called for the successful writes, got %q", writes)
+	}
+}
+
+func TestDo(t *testing.T) {
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if req.Method != "PUT" || !strings.HasSuffix(req.URL.Path, "/fleets/1234/") {
+			t.Fatalf("Unexpected request %s %s", req.Method, req.URL)
+		}
+		if req.Header.Get("Authorization") != "Bearer token" || req.Header.Get("User-Agent") == "" {
+			t.Fatalf("Expected the standard headers, got %v", req.Header)
+		}
+		body, _ := io.ReadAll(req.Body)
+		if string(body) != `{"motd": "hi"}` {
+			t.Fatalf("Unexpected body '%s'", body)
+		}
+		return stubResponse(404, `{"error": "Fleet not found"}`, "Expires", time.Now().UTC().Add(time.Hour).Format(http.TimeFormat)), nil
+	})
+	e.AccessToken = "token"
+	for i := 0; i < 2; i++ {
+		resp, err := e.Do("PUT", "fleets/%d", strings.NewReader(`{"motd": "hi"}`), 1234)
+		if err != nil {
+			t.Fatalf("Expected the response as-is, got %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != 404 || string(body) != `{"error": "Fleet not found"}` {
+			t.Fatalf("Expected the unread response, got %d '%s'", resp.StatusCode, body)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("Expected the responses not to be cached, made %d requests", calls)
+	}
+}
+
+func TestNormalizePath(t *testing.T) {
+	expected := BaseURL + "latest/characters/123/"
+	for _, path := range []string{"characters/123", "/characters/123/", " characters/123 ", "characters//123", "\tcharacters/ 123/"} {
+		if got := buildVersionURL("latest", path); got != expected {
+			t.Fatalf("Path %q: expected %s, got %s", path, expected, got)
+		}
+	}
+	if got := NormalizePath("/characters/123/assets/?page=2"); got != "characters/123/assets?page=2" {
+		t.Fatalf("Expected the query string to be kept, got %s", got)
+	}
+
+	var urls []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		urls = append(urls, req.URL.String())
+		return stubResponse(200, `{}`), nil
+	})
+	e.Get("/characters/%d/", 123, NoCache())
+	e.Post(" characters/123 ", "{}")
+	e.Delete("characters//123")
+	for _, u := range urls {
+		if u != expected {
+			t.Fatalf("Expected every method to normalize the path, got %q", urls)
+		}
+	}
+}
+
+func TestGetWithVersionFallback(t *testing.T) {
+	var versions []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		version := strings.Split(strings.TrimPrefix(req.URL.Path, "/"), "/")[0]
+		versions = append(versions, version)
+		if version == "v1" {
+			return stubResponse(200, `{"players": 30000}`), nil
+		}
+		return stubResponse(500, `{"error": "Internal error"}`), nil
+	})
+	data, err := e.GetWithVersionFallback("status", []string{"latest", "v2", "v1", "legacy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Path("players").Data().(float64) != 30000 {
+		t.Fatalf("Unexpected data: %s", data)
+	}
+	if strings.Join(versions, ",") != "latest,v2,v1" {
+		t.Fatalf("Expected the versions to be tried in order until one worked, got %q", versions)
+	}
+
+	versions = nil
+	var esiErr *ESIError
+	if _, err := e.GetWithVersionFallback("status", []string{"latest", "v2"}); !errors.As(err, &esiErr) || !strings.Contains(esiErr.URL, "/v2/") {
+		t.Fatalf("Expected the last version's error, got %v", err)
+	}
+	if _, err := e.GetWithVersionFallback("status", nil); err == nil {
+		t.Fatal("Expected an error without any versions")
+	}
+}
+
+func TestGetWithFailover(t *testing.T) {
+	var hosts []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		hosts = append(hosts, req.URL.Host)
+		switch req.URL.Host {
+		case "primary.example.com":
+			return nil, errors.New("connection refused")
+		case "mirror.example.com":
+			return stubResponse(503, `{"error": "unavailable"}`), nil
+		}
+		if strings.HasSuffix(req.URL.Path, "/characters/1/") {
+			return stubResponse(404, `{"error": "Character not found"}`), nil
+		}
+		return stubResponse(200, `{"players": 30000}`), nil
+	})
+	e.BreakerThreshold = 1
+	e.BaseURLs = []string{"https://primary.example.com", "https://mirror.example.com/", BaseURL}
+	for i := 0; i < 2; i++ {
+		hosts = nil
+		data, err := e.GetWithFailover("status", NoCache())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data.Path("players").Data().(float64) != 30000 {
+			t.Fatalf("Unexpected data: %s", data)
+		}
+		// the second time, the mirror's circuit breaker is open after its 503
+		expected := "primary.example.com,mirror.example.com,esi.tech.ccp.is"
+		if i == 1 {
+			expected = "primary.example.com,esi.tech.ccp.is"
+		}
+		if strings.Join(hosts, ",") != expected {
+			t.Fatalf("Call %d: expected the base URLs to be tried in order until one worked, got %q", i, hosts)
+		}
+	}
+
+	hosts = nil
+	e.BaseURLs = []string{BaseURL, "https://mirror.example.com"}
+	var esiErr *ESIError
+	if _, err := e.GetWithFailover("characters/%d", 1); !errors.As(err, &esiErr) || esiErr.StatusCode != 404 {
+		t.Fatalf("Expected the 404 to be returned, got %v", err)
+	}
+	if len(hosts) != 1 {
+		t.Fatalf("Expected a 404 not to fail over, got calls to %q", hosts)
+	}
+
+	e = newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		hosts = append(hosts, req.URL.Host)
+		if req.URL.Host == "hanging.example.com" {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		return stubResponse(200, `{"players": 30000}`), nil
+	})
+	e.HTTPClient().Timeout = 20 * time.Millisecond
+	e.BaseURLs = []string{"https://hanging.example.com", BaseURL}
+	if data, err := e.GetWithFailover("status"); err != nil || data.Path("players").Data().(float64) != 30000 {
+		t.Fatalf("Expected a base URL that timed out to fail over, got %v and %v", data, err)
+	}
+	hosts = nil
+	if _, err := e.GetWithFailover("status", NoCache(), WithDeadline(time.Now().Add(5*time.Millisecond))); !errors.Is(err, context.DeadlineExceeded) || len(hosts) != 1 {
+		t.Fatalf("Expected a call past the caller's own deadline not to fail over, got %v after calls to %q", err, hosts)
+	}
+}
+
+func TestClone(t *testing.T) {
+	expires := time.Now().UTC().Add(time.Hour).Format(http.TimeFormat)
+	var tokens []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		token := req.Header.Get("Authorization")
+		tokens = append(tokens, token)
+		if req.URL.String() == VerifyURL {
+			return stubResponse(200, `{"CharacterID": 90000002}`), nil
+		}
+		return stubResponse(200, `{"token": "`+token+`"}`, "Expires", expires), nil
+	})
+	e.AccessToken = "a"
+	e.CharacterID = 90000001
+	e.CacheTTLOverrides = map[string]time.Duration{"status": time.Minute}
+	if _, err := e.Get("fleets/%d", 1234); err != nil {
+		t.Fatal(err)
+	}
+
+	clone := e.Clone()
+	clone.AccessToken = "b"
+	clone.CacheTTLOverrides["status"] = time.Hour
+	if clone.CharacterID != 0 {
+		t.Fatalf("Expected the clone's character to be cleared, got %d", clone.CharacterID)
+	}
+	data, err := clone.Get("fleets/%d", 1234)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Path("token").Data().(string) != "Bearer b" {
+		t.Fatalf("Expected the clone not to be given the original's cached response, got %s", data)
+	}
+	if id, err := clone.characterID(); err != nil || id != 90000002 {
+		t.Fatalf("Expected the clone's character to be looked up for its token, got %d, %v", id, err)
+	}
+	if e.AccessToken != "a" || e.CharacterID != 90000001 || e.CacheTTLOverrides["status"] != time.Minute {
+		t.Fatalf("Expected the original to be left alone, got %+v", e)
+	}
+
+	shared := e.CloneSharingCache()
+	calls := len(tokens)
+	if _, err := shared.Get("fleets/%d", 1234); err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != calls {
+		t.Fatal("Expected CloneSharingCache's copy to use the original's cached response")
+	}
+}
+
+func TestHTTPClient(t *testing.T) {
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return stubResponse(200, `{}`), nil
+	})
+	e.HTTPClient().Timeout = time.Minute
+	if e.client.Timeout != time.Minute {
+		t.Fatal("Expected the client's settings to be changed")
+	}
+	e.HTTPClient().Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return stubResponse(200, `{"swapped": true}`), nil
+	})
+	data, err := e.Get("status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 || data.Path("swapped").Data() != true {
+		t.Fatalf("Expected the swapped transport to be used, got %s", data)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return stubResponse(503, `{"error": "unavailable"}`), nil
+	})
+	e.BreakerThreshold = 2
+	e.BreakerCooldown = time.Hour
+	e.Get("status")
+	e.Get("status")
+	if _, err := e.Get("status"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("Expected 2 requests before the circuit opened, made %d", calls)
+	}
+}
+
+func TestCircuitBreakerRecovery(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)}
+	calls := 0
+	var status int
+	var failWith error
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if failWith != nil {
+			return nil, failWith
+		}
+		return stubResponse(status, `{"players": 30000}`), nil
+	})
+	e.SetClock(clock)
+	e.BreakerThreshold = 2
+	e.BreakerCooldown = time.Minute
+	steps := []struct {
+		name     string
+		advance  time.Duration
+		status   int
+		failWith error
+		calls    int
+		expected error
+	}{
+		{"first failure", 0, 503, nil, 1, nil},
+		{"second failure opens the circuit", 0, 503, nil, 2, nil},
+		{"open circuit fails fast", 0, 200, nil, 2, ErrCircuitOpen},
+		{"still open during the cooldown", 30 * time.Second, 200, nil, 2, ErrCircuitOpen},
+		{"failed probe after the cooldown", time.Minute, 503, nil, 3, nil},
+		{"failed probe reopens the circuit", 0, 200, nil, 3, ErrCircuitOpen},
+		{"probe with an error that isn't ESI's", 2 * time.Minute, 0, errors.New("connection reset"), 4, nil},
+		{"released probe lets another through", 0, 200, nil, 5, nil},
+		{"recovered circuit lets calls through", 0, 200, nil, 6, nil},
+	}
+	for _, step := range steps {
+		clock.now = clock.now.Add(step.advance)
+		status, failWith = step.status, step.failWith
+		_, err := e.Get("status", NoCache())
+		if step.expected != nil && !errors.Is(err, step.expected) {
+			t.Fatalf("%s: expected %v, got %v", step.name, step.expected, err)
+		}
+		if step.expected == nil && errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("%s: expected the call to be let through", step.name)
+		}
+		if calls != step.calls {
+			t.Fatalf("%s: expected %d requests in total, made %d", step.name, step.calls, calls)
+		}
+	}
+}
+
+func TestRouteTimeouts(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		select {
+		case <-time.After(50 * time.Millisecond):
+			return stubResponse(200, `{}`), nil
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	})
+	e.HTTPClient().Timeout = 20 * time.Millisecond
+	e.BreakerThreshold = 1
+	e.BreakerCooldown = time.Hour
+	e.RouteTimeouts = map[string]time.Duration{
+		"markets":           time.Minute,
+		"markets/10000002":  5 * time.Millisecond,
+		"universe/systems/": time.Minute,
+	}
+	if _, err := e.Get("markets/%d/orders", 10000001); err != nil {
+		t.Fatalf("Expected the route's timeout to be used in place of the client's, got %s", err)
+	}
+	if _, err := e.Get("markets/%d/orders", 10000002); err == nil {
+		t.Fatal("Expected the longest matching prefix's timeout")
+	}
+	if _, err := e.Get("universe/systems/%d", 30000142); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected the timed out call to open the circuit breaker, got %v", err)
+	}
+
+	e = newStubbedESI(e.client.Transport.(roundTripFunc))
+	e.HTTPClient().Timeout = 20 * time.Millisecond
+	if _, err := e.Get("status"); err == nil {
+		t.Fatal("Expected the client's timeout for routes without one")
+	}
+}
+
+func TestGetRaw(t *testing.T) {
+	var accepts []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		accepts = append(accepts, req.Header.Get("Accept"))
+		if strings.Contains(req.URL.Path, "missing") {
+			return stubResponse(404, `{"error": "not found"}`), nil
+		}
+		return stubResponse(200, "type_id,price\n34,5.5\n", "Content-Type", "text/csv"), nil
+	})
+	body, err := e.GetRaw("markets/%d/prices", 10000002, WithAccept("text/csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "type_id,price\n34,5.5\n" || accepts[0] != "text/csv" {
+		t.Fatalf("Expected the CSV body as-is, got %q with Accept %q", body, accepts)
+	}
+	var contentTypeErr *ContentTypeError
+	if _, err := e.Get("markets/%d/prices", 10000002); !errors.As(err, &contentTypeErr) || accepts[1] != "application/json" {
+		t.Fatalf("Expected Get to still ask for JSON, got %v with Accept %q", err, accepts)
+	}
+	var esiErr *ESIError
+	if _, err := e.GetRaw("missing"); !errors.As(err, &esiErr) || esiErr.StatusCode != 404 || !strings.HasSuffix(esiErr.URL, "/missing/") {
+		t.Fatalf("Expected an *ESIError, got %v", err)
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "declared") {
+			resp := stubResponse(200, `[1, 2, 3]`)
+			resp.ContentLength = 1 << 30
+			return resp, nil
+		}
+		if strings.Contains(req.URL.Path, "large") {
+			return stubResponse(200, "["+strings.Repeat("1, ", 100)+"1]"), nil
+		}
+		return stubResponse(200, `[1, 2, 3]`), nil
+	})
+	e.MaxResponseBytes = 64
+	if _, err := e.Get("small"); err != nil {
+		t.Fatalf("Expected a body under the limit to be read, got %v", err)
+	}
+	var tooLarge *ResponseTooLargeError
+	for _, path := range []string{"large", "declared"} {
+		if _, err := e.Get(path); !errors.As(err, &tooLarge) || tooLarge.Limit != 64 {
+			t.Fatalf("Expected a *ResponseTooLargeError for %s, got %v", path, err)
+		}
+	}
+	if _, err := e.GetRaw("large"); !errors.As(err, &tooLarge) {
+		t.Fatalf("Expected GetRaw to enforce the limit, got %v", err)
+	}
+	e.MaxResponseBytes = 0
+	if _, err := e.Get("large"); err != nil {
+		t.Fatalf("Expected no limit, got %v", err)
+	}
+}
+
+func TestGetStaleOK(t *testing.T) {
+	expired := time.Now().UTC().Add(-time.Hour).Format(http.TimeFormat)
+	var calls int32
+	release := make(chan struct{})
+	refreshed := make(chan struct{}, 10)
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			<-release
+			defer func() { refreshed <- struct{}{} }()
+		}
+		return stubResponse(200, `{"players": 30000}`, "Expires", expired), nil
+	})
+	if _, err := e.GetStaleOK("status"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for i := 0; i < 3; i++ {
+		data, err := e.GetStaleOK("status")
+		if err != nil || data == nil {
+			t.Fatalf("Expected stale data, got %v, %v", data, err)
+		}
+	}
+	close(release)
+	<-refreshed
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("Expected 1 request and 1 background refresh, made %d requests", n)
+	}
+}
+
+func TestServeStaleOnError(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)}
+	status := 200
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if status != 200 {
+			return stubResponse(status, `{"error": "unavailable"}`), nil
+		}
+		return stubResponse(200, `{"players": 30000}`, "Expires", clock.now.Add(time.Minute).Format(http.TimeFormat)), nil
+	})
+	e.SetClock(clock)
+	e.BreakerThreshold = 0
+	e.MaxRetries = 0
+	if _, err := e.Get("status"); err != nil {
+		t.Fatal(err)
+	}
+	clock.now = clock.now.Add(2 * time.Minute)
+	status = 503
+
+	var esiErr *ESIError
+	if data, err := e.Get("status"); data != nil || !errors.As(err, &esiErr) {
+		t.Fatalf("Expected just the error without ServeStaleOnError, got %v and %v", data, err)
+	}
+	e.ServeStaleOnError = true
+	data, err := e.Get("status")
+	var staleErr *StaleError
+	if !errors.As(err, &staleErr) || !errors.As(err, &esiErr) || esiErr.StatusCode != 503 {
+		t.Fatalf("Expected a *StaleError wrapping the *ESIError, got %v", err)
+	}
+	if data == nil || data.Path("players").Data().(float64) != 30000 || !staleErr.Expires.Equal(clock.now.Add(-time.Minute)) {
+		t.Fatalf("Expected the expired data, got %v expiring %s", data, staleErr.Expires)
+	}
+
+	status = 404
+	if data, err := e.Get("status"); data != nil || errors.As(err, &staleErr) {
+		t.Fatalf("Expected a 404 not to fall back to the expired data, got %v and %v", data, err)
+	}
+	status = 503
+	if data, err := e.Get("status", NoCache()); data != nil || errors.As(err, &staleErr) {
+		t.Fatalf("Expected NoCache not to fall back to the expired data, got %v and %v", data, err)
+	}
+
+	status = 0
+	e.SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}))
+	e.HTTPClient().Timeout = 20 * time.Millisecond
+	if data, err := e.Get("status"); data == nil || !errors.As(err, &staleErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected a call that hit the client's timeout to fall back to the expired data, got %v and %v", data, err)
+	}
+	e.HTTPClient().Timeout = 0
+	e.RouteTimeouts = map[string]time.Duration{"status": 20 * time.Millisecond}
+	if data, err := e.Get("status"); data == nil || !errors.As(err, &staleErr) {
+		t.Fatalf("Expected a call that hit its route's timeout to fall back to the expired data, got %v and %v", data, err)
+	}
+	e.RouteTimeouts = nil
+	if data, err := e.Get("status", WithDeadline(time.Now().Add(20*time.Millisecond))); data != nil || errors.As(err, &staleErr) {
+		t.Fatalf("Expected a call past the caller's own deadline not to fall back to the expired data, got %v and %v", data, err)
+	}
+
+	e.SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return stubResponse(200, "["+strings.Repeat("1, ", 100)+"1]"), nil
+	}))
+	e.MaxResponseBytes = 64
+	var tooLarge *ResponseTooLargeError
+	if data, err := e.Get("status"); data != nil || errors.As(err, &staleErr) || !errors.As(err, &tooLarge) {
+		t.Fatalf("Expected a response that was too large not to fall back to the expired data, got %v and %v", data, err)
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	expired := time.Now().UTC().Add(-time.Hour).Format(http.TimeFormat)
+	expires := time.Now().UTC().Add(time.Hour).Format(http.TimeFormat)
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		switch calls {
+		case 1:
+			return stubResponse(200, `{"players": 30000}`, "Expires", expired, "ETag", `"abc"`), nil
+		case 2:
+			if req.Header.Get("If-None-Match") != `"abc"` {
+				t.Fatalf("Expected conditional request, got headers %v", req.Header)
+			}
+			return stubResponse(304, "", "Expires", expires, "ETag", `"abc"`), nil
+		}
+		t.Fatal("Unexpected request after data was revalidated")
+		return nil, nil
+	})
+	for i := 0; i < 3; i++ {
+		data, err := e.Get("status")
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if data.Path("players").Data().(float64) != 30000 {
+			t.Fatalf("Unexpected data: %s", data)
+		}
+	}
+	expected := CacheStats{Hits: 1, ConditionalHits: 1, Misses: 1}
+	if stats := e.Stats(); stats != expected {
+		t.Fatalf("Expected stats %+v, got %+v", expected, stats)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	started := make(chan struct{})
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		close(started)
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+	done := make(chan error)
+	go func() {
+		_, err := e.Get("status")
+		done <- err
+	}()
+	<-started
+	e.Shutdown()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRefreshAccessToken(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		expected error
+	}{
+		{400, `{"error": "invalid_grant", "error_description": "Invalid refresh token"}`, ErrRefreshTokenInvalid},
+		{503, `{"error": "temporarily_unavailable"}`, nil},
+	}
+	for _, test := range tests {
+		e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+			return stubResponse(test.status, test.body), nil
+		})
+		e.RefreshToken = "refresh"
+		err := e.RefreshAccessToken()
+		if err == nil {
+			t.Fatalf("Status %d: expected an error", test.status)
+		}
+		if errors.Is(err, ErrRefreshTokenInvalid) != (test.expected == ErrRefreshTokenInvalid) {
+			t.Fatalf("Status %d: unexpected error %v", test.status, err)
+		}
+	}
+}
+
+func TestGetAuthorizeURLMissingClientData(t *testing.T) {
+	tests := []struct {
+		clientID, secret, callback string
+		expected                   error
+	}{
+		{"", "clientSecret", "http://localhost/callback", ErrMissingClientID},
+		{"clientID", "", "http://localhost/callback", ErrMissingClientSecret},
+		{"clientID", "clientSecret", "", ErrMissingCallbackURL},
+		{"", "", "", ErrMissingClientID},
+		{"clientID", "clientSecret", "http://localhost/callback", nil},
+	}
+	for _, test := range tests {
+		e := New(test.clientID, test.secret, test.callback)
+		authURL, err := e.GetAuthorizeURL()
+		if !errors.Is(err, test.expected) || (err == nil) != (test.expected == nil) {
+			t.Fatalf("%+v: expected %v, got %v", test, test.expected, err)
+		}
+		if (authURL == "") != (test.expected != nil) {
+			t.Fatalf("%+v: unexpected URL %q", test, authURL)
+		}
+	}
+}
+
+func TestSSOError(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.String() {
+		case TokenURL:
+			return stubResponse(503, `{"error": "temporarily_unavailable", "error_description": "Try again later"}`), nil
+		case VerifyURL:
+			return stubResponse(401, `{"error": "invalid_token"}`), nil
+		}
+		return nil, errors.New("connection refused")
+	})
+	e.RefreshToken = "refresh"
+	var ssoErr *SSOError
+	err := e.RefreshAccessToken()
+	if !errors.As(err, &ssoErr) || ssoErr.StatusCode != 503 || ssoErr.Code != "temporarily_unavailable" || ssoErr.Description != "Try again later" {
+		t.Fatalf("Expected an *SSOError with the SSO's error, got %v", err)
+	}
+	var esiErr *ESIError
+	if errors.As(err, &esiErr) || errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("Expected only an *SSOError, got %v", err)
+	}
+
+	e.AccessToken = "token"
+	if _, err := e.WhoAmI(); !errors.As(err, &ssoErr) || ssoErr.StatusCode != 401 || ssoErr.URL != VerifyURL {
+		t.Fatalf("Expected an *SSOError from whoami, got %v", err)
+	}
+
+	if _, err := fetchJWKS(e); !errors.As(err, &ssoErr) || ssoErr.StatusCode != 0 || ssoErr.Err == nil {
+		t.Fatalf("Expected an *SSOError for a call that couldn't be made, got %v", err)
+	}
+}
+
+func TestESIError(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		return stubResponse(404, `{"error": "Character not found"}`, RequestIDHeader, "abc-123"), nil
+	})
+	_, err := e.Get("characters/%d", 1)
+	var esiErr *ESIError
+	if !errors.As(err, &esiErr) {
+		t.Fatalf("Expected an *ESIError, got %v", err)
+	}
+	if esiErr.StatusCode != 404 || esiErr.Message != "Character not found" || esiErr.RequestID != "abc-123" {
+		t.Fatalf("Unexpected error contents: %+v", esiErr)
+	}
+	if !strings.Contains(err.Error(), "abc-123") {
+		t.Fatalf("Expected the request ID in the error message, got '%s'", err)
+	}
+}
+
+func TestEmptyResultIsNotAnError(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "/contracts/") {
+			return stubResponse(200, `[]`, "Content-Type", "application/json"), nil
+		}
+		return stubResponse(403, `{"error": "Token is not valid for scope(s)"}`, "Content-Type", "application/json"), nil
+	})
+	data, err := e.Get("characters/%d/contracts", 90000001)
+	if err != nil {
+		t.Fatalf("Expected an empty array to be a success, got %v", err)
+	}
+	children, err := data.Children()
+	if err != nil || len(children) != 0 {
+		t.Fatalf("Expected an empty array, got '%s'", data)
+	}
+
+	data, err = e.Get("characters/%d/orders", 90000001)
+	var esiErr *ESIError
+	if !errors.As(err, &esiErr) || esiErr.StatusCode != 403 {
+		t.Fatalf("Expected an *ESIError for the error object, got %v", err)
+	}
+	if data != nil {
+		t.Fatalf("Expected no data with the error, got '%s'", data)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		return stubResponse(200, `{"players": 30`+strings.Repeat(" ", 300), "Content-Type", "application/json"), nil
+	})
+	_, err := e.Get("status")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a *ParseError, got %v", err)
+	}
+	if parseErr.StatusCode != 200 || len(parseErr.Snippet) != parseErrorSnippetLength || !strings.HasPrefix(parseErr.Snippet, `{"players"`) {
+		t.Fatalf("Unexpected error contents: %+v", parseErr)
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "/2/") {
+			return stubResponse(404, `{"error": "Type not found"}`), nil
+		}
+		return stubResponse(200, `{"name": "Tritanium"}`), nil
+	})
+	results, err := e.GetMany([]string{"universe/types/1", "universe/types/2", "universe/types/3"})
+	var multiErr *MultiError
+	if !errors.As(err, &multiErr) {
+		t.Fatalf("Expected a *MultiError, got %v", err)
+	}
+	if len(multiErr.Errors) != 1 || multiErr.Errors["universe/types/2"] == nil {
+		t.Fatalf("Expected one failure for universe/types/2, got %v", multiErr.Errors)
+	}
+	var esiErr *ESIError
+	if !errors.As(err, &esiErr) || esiErr.StatusCode != 404 {
+		t.Fatalf("Expected the individual *ESIError to be reachable, got %v", err)
+	}
+	if len(results) != 2 || results["universe/types/1"] == nil || results["universe/types/3"] == nil {
+		t.Fatalf("Expected the two successful results, got %v", results)
+	}
+}
+
+func TestGetManyDeadline(t *testing.T) {
+	var calls int32
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		_, hasDeadline := req.Context().Deadline()
+		if atomic.AddInt32(&calls, 1) == 1 || !hasDeadline {
+			return stubResponse(200, `{"name": "Tritanium"}`), nil
+		}
+		// the second call hangs until the deadline passes
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+	e.MaxConcurrency = 1
+	paths := []string{"universe/types/1", "universe/types/2", "universe/types/3"}
+	results, err := e.GetManyWithOptions(paths, WithDeadline(time.Now().Add(50*time.Millisecond)))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the deadline to be exceeded, got %v", err)
+	}
+	var multiErr *MultiError
+	if len(results) != 1 || !errors.As(err, &multiErr) || len(multiErr.Errors) != 2 {
+		t.Fatalf("Expected the result fetched before the deadline and two failures, got %v, %v", results, err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("Expected no calls after the deadline, made %d", n)
+	}
+}
+
+// fakeClock is a Clock that only moves when told to, or when something waits on it
+type fakeClock struct {
+	now time.Time
+	// waits are the durations passed to After
+	waits []time.Duration
+	// blockAt, if set, is the wait that never ends, for stopping loops
+	blockAt int
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// After records the wait and moves the clock forward by it straight away
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.waits = append(c.waits, d)
+	if c.blockAt > 0 && len(c.waits) >= c.blockAt {
+		return nil
+	}
+	c.now = c.now.Add(d)
+	fired := make(chan time.Time, 1)
+	fired <- c.now
+	return fired
+}
+
+func TestClockControlsCacheExpiry(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)}
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		expires := clock.now.Add(time.Hour).Format(http.TimeFormat)
+		return stubResponse(200, `{"players": 30000}`, "Expires", expires), nil
+	})
+	e.SetClock(clock)
+	e.Get("status")
+	clock.now = clock.now.Add(30 * time.Minute)
+	e.Get("status")
+	if calls != 1 {
+		t.Fatalf("Expected the response to still be cached, made %d requests", calls)
+	}
+	if remaining, ok := e.TimeUntilExpiry("status"); !ok || remaining != 30*time.Minute {
+		t.Fatalf("Expected 30m until expiry, got %s %v", remaining, ok)
+	}
+	clock.now = clock.now.Add(time.Hour)
+	e.Get("status")
+	if calls != 2 {
+		t.Fatalf("Expected the response to have expired, made %d requests", calls)
+	}
+}
+
+func TestNonJSONContentType(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		return stubResponse(502, "<html><body><h1>502 Bad Gateway</h1></body></html>", "Content-Type", "text/html", RequestIDHeader, "abc-123"), nil
+	})
+	_, err := e.Get("status")
+	var esiErr *ESIError
+	if !errors.As(err, &esiErr) || esiErr.StatusCode != 502 || esiErr.RequestID != "abc-123" || esiErr.ContentType != "text/html" {
+		t.Fatalf("Expected an *ESIError with the request ID and content type, got %v", err)
+	}
+	var ctErr *ContentTypeError
+	if !errors.As(err, &ctErr) {
+		t.Fatalf("Expected a *ContentTypeError, got %v", err)
+	}
+	if ctErr.StatusCode != 502 || ctErr.ContentType != "text/html" {
+		t.Fatalf("Unexpected error contents: %+v", ctErr)
+	}
+
+	e = newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		return stubResponse(200, "<html><body>Maintenance</body></html>", "Content-Type", "text/html"), nil
+	})
+	if _, err := e.Get("status"); !errors.As(err, &ctErr) || ctErr.StatusCode != 200 {
+		t.Fatalf("Expected a *ContentTypeError for a successful response, got %v", err)
+	}
+}
+
+// recordingLogger is a Logger that keeps the key-values of each message, keyed by message
+type recordingLogger struct {
+	mu   sync.Mutex
+	logs map[string][]map[string]interface{}
+}
+
+func (r *recordingLogger) record(msg string, keyvals []interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fields := make(map[string]interface{})
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fields[keyvals[i].(string)] = keyvals[i+1]
+	}
+	r.logs[msg] = append(r.logs[msg], fields)
+}
+
+func (r *recordingLogger) Debug(msg string, keyvals ...interface{}) { r.record(msg, keyvals) }
+func (r *recordingLogger) Info(msg string, keyvals ...interface{})  { r.record(msg, keyvals) }
+func (r *recordingLogger) Warn(msg string, keyvals ...interface{})  { r.record(msg, keyvals) }
+func (r *recordingLogger) Error(msg string, keyvals ...interface{}) { r.record(msg, keyvals) }
+
+func TestLogger(t *testing.T) {
+	expires := time.Now().UTC().Add(time.Hour).Format(http.TimeFormat)
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		return stubResponse(200, `{"name": "Jita"}`, "Expires", expires), nil
+	})
+	logger := &recordingLogger{logs: make(map[string][]map[string]interface{})}
+	e.SetLogger(logger)
+	for i := 0; i < 2; i++ {
+		if _, err := e.Get("universe/systems/%d", 30000142); err != nil {
+			t.Fatal(err)
+		}
+	}
+	url := e.buildURL("universe/systems/30000142")
+
+	calls := logger.logs["Made call to ESI"]
+	if len(calls) != 1 {
+		t.Fatalf("Expected 1 call to be logged, got %d", len(calls))
+	}
+	call := calls[0]
+	if call["method"] != "GET" || call["url"] != url || call["status"] != 200 || call["cacheHit"] != false {
+		t.Fatalf("Unexpected call fields: %v", call)
+	}
+	if _, ok := call["duration"].(time.Duration); !ok {
+		t.Fatalf("Expected a duration, got %v", call["duration"])
+	}
+
+	hits := logger.logs["Returning cached value"]
+	if len(hits) != 1 || hits[0]["url"] != url || hits[0]["cacheHit"] != true {
+		t.Fatalf("Unexpected cache hit logs: %v", hits)
+	}
+	if len(logger.logs["Storing response in cache"]) != 1 {
+		t.Fatalf("Expected the cache to log through the logger, got %v", logger.logs)
+	}
+}
+
+func TestFormatKeyvals(t *testing.T) {
+	got := formatKeyvals("Made call", []interface{}{"url", "https://esi/", "status", 200, "error", "bad thing", "dangling"})
+	expected := `Made call url=https://esi/ status=200 error="bad thing" dangling=(missing)`
+	if got != expected {
+		t.Fatalf("Expected '%s', got '%s'", expected, got)
+	}
+}
+
+func TestGetPublic(t *testing.T) {
+	var authorization []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		authorization = append(authorization, req.Header.Get("Authorization"))
+		return stubResponse(200, `{}`), nil
+	})
+	e.AccessToken = "token"
+	if _, err := e.GetPublic("universe/systems/%d", 30000142); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Get("characters/%d/wallet", 90000001); err != nil {
+		t.Fatal(err)
+	}
+	if len(authorization) != 2 || authorization[0] != "" || authorization[1] != "Bearer token" {
+		t.Fatalf("Expected only the authenticated call to send the token, got %q", authorization)
+	}
+}
+
+func TestRequestOptions(t *testing.T) {
+	var requests []*http.Request
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req)
+		return stubResponse(200, `[]`, "Expires", time.Now().UTC().Add(time.Hour).Format(http.TimeFormat), "ETag", `"abc"`), nil
+	})
+	params := url.Values{"datasource": {"tranquility"}}
+	if _, err := e.Get("characters/%d/contracts", 90000001, WithParams(params)); err != nil {
+		t.Fatal(err)
+	}
+	if got := requests[0].URL.RawQuery; got != "datasource=tranquility" {
+		t.Fatalf("Expected the params in the query, got '%s'", got)
+	}
+	// the cached response is used unless NoCache is given
+	if _, err := e.Get("characters/%d/contracts", 90000001, WithParams(params)); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("Expected the cached response to be used, got %d requests", len(requests))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := e.Get("characters/%d/contracts", 90000001, WithParams(params), NoCache(), WithContext(ctx),
+		WithHeaders(map[string]string{"Accept-Language": "de"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("Expected NoCache to make a request, got %d requests", len(requests))
+	}
+	req := requests[1]
+	if req.Header.Get("If-None-Match") != "" {
+		t.Fatal("Expected NoCache not to send the ETag")
+	}
+	if req.Header.Get("Accept-Language") != "de" {
+		t.Fatalf("Expected the header to be sent, got %v", req.Header)
+	}
+	cancel()
+	if req.Context().Err() == nil {
+		t.Fatal("Expected the request to use the context")
+	}
+}
+
+func TestRequestOptionsInOtherMethods(t *testing.T) {
+	var urls []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		urls = append(urls, req.URL.String())
+		if req.Header.Get("Accept-Language") != "de" {
+			t.Fatalf("Expected the header on %s, got %v", req.URL, req.Header)
+		}
+		return stubResponse(200, `{}`, "Expires", time.Now().UTC().Add(time.Hour).Format(http.TimeFormat)), nil
+	})
+	params := WithParams(url.Values{"datasource": {"tranquility"}})
+	headers := WithHeaders(map[string]string{"Accept-Language": "de"})
+	if _, err := e.GetStaleOK("status", params, headers); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.GetWithVersionFallback("status", []string{"v1"}, params, headers); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := e.GetIfNoneMatch("", "status", params, headers); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := e.Do("GET", "status", nil, params, headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if _, ok := e.TimeUntilExpiry("status", params); !ok {
+		t.Fatal("Expected TimeUntilExpiry to find the response cached with the params")
+	}
+	urls = append(urls, e.CurlFor("GET", "status", params, headers))
+	if len(urls) != 5 {
+		t.Fatalf("Expected 4 requests and a curl command, got %q", urls)
+	}
+	for _, u := range urls {
+		if strings.Contains(u, "%!") || !strings.Contains(u, "status/?datasource=tranquility") {
+			t.Fatalf("Expected the options to be applied, got '%s'", u)
+		}
+	}
+}
+
+func TestCallerDeadlineDoesNotOpenCircuit(t *testing.T) {
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			<-req.Context().Done()
+			return nil, &url.Error{Op: "Get", URL: req.URL.String(), Err: req.Context().Err()}
+		}
+		return stubResponse(200, `{}`), nil
+	})
+	e.BreakerThreshold = 1
+	if _, err := e.Get("status", WithDeadline(time.Now().Add(10*time.Millisecond))); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the deadline to be exceeded, got %v", err)
+	}
+	if _, err := e.Get("status"); err != nil {
+		t.Fatalf("Expected the caller's deadline not to open the circuit, got %v", err)
+	}
+}
+
+func TestReset(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		return stubResponse(200, `{}`, "Expires", time.Now().UTC().Add(time.Hour).Format(http.TimeFormat)), nil
+	})
+	e.AccessToken = "access"
+	e.RefreshToken = "refresh"
+	e.CharacterID = 90000001
+	e.Scope = "esi-wallet.read_character_wallet.v1"
+	if _, err := e.Get("characters/%d/wallet", 90000001); err != nil {
+		t.Fatal(err)
+	}
+
+	e.Reset(false)
+	if e.AccessToken != "" || e.RefreshToken != "" || e.CharacterID != 0 {
+		t.Fatalf("Expected the tokens and character to be cleared, got %+v", e)
+	}
+	if e.ClientID != "clientID" || e.Scope == "" {
+		t.Fatal("Expected the client data and scopes to be kept")
+	}
+	if _, ok := e.TimeUntilExpiry("characters/%d/wallet", 90000001); !ok {
+		t.Fatal("Expected the cached response to be kept")
+	}
+
+	e.Reset(true)
+	if _, ok := e.TimeUntilExpiry("characters/%d/wallet", 90000001); ok {
+		t.Fatal("Expected the cache to be cleared")
+	}
+}
+
+func TestCacheKeyFunc(t *testing.T) {
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return stubResponse(200, `{}`, "Expires", time.Now().UTC().Add(time.Hour).Format(http.TimeFormat)), nil
+	})
+	e.CacheKeyFunc = func(method, u string) string {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return u
+		}
+		query := parsed.Query()
+		query.Del("nonce")
+		parsed.RawQuery = query.Encode()
+		return parsed.String()
+	}
+	for _, nonce := range []string{"1", "2"} {
+		if _, err := e.GetWithParams("markets/%d/orders", url.Values{"nonce": {nonce}, "type_id": {"34"}}, 10000002); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("Expected the calls to share a cache entry, got %d calls", calls)
+	}
+	if _, err := e.GetWithParams("markets/%d/orders", url.Values{"type_id": {"35"}}, 10000002); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("Expected a different query to miss the cache, got %d calls", calls)
+	}
+}
+
+func TestParamsOrderSharesCacheEntry(t *testing.T) {
+	var urls []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		urls = append(urls, req.URL.String())
+		return stubResponse(200, `[]`, "Expires", time.Now().UTC().Add(time.Hour).Format(http.TimeFormat)), nil
+	})
+	first := url.Values{}
+	first.Set("type_id", "34")
+	first.Set("order_type", "sell")
+	second := url.Values{}
+	second.Set("order_type", "sell")
+	second.Set("type_id", "34")
+	for _, params := range []url.Values{first, second} {
+		if _, err := e.GetWithParams("markets/%d/orders", params, 10000002); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, path := range []string{"markets/10000002/orders?type_id=34&order_type=sell", "markets/10000002/orders/?order_type=sell&type_id=34"} {
+		if _, err := e.Get(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := "https://esi.tech.ccp.is/latest/markets/10000002/orders/?order_type=sell&type_id=34"
+	if len(urls) != 1 || urls[0] != expected {
+		t.Fatalf("Expected a single call to '%s', got %q", expected, urls)
+	}
+}
+
+func TestCompressed(t *testing.T) {
+	var bodies, encodings []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		encoding := req.Header.Get("Content-Encoding")
+		encodings = append(encodings, encoding)
+		var reader io.Reader = req.Body
+		if encoding == "gzip" {
+			gz, err := gzip.NewReader(req.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reader = gz
+		}
+		body, _ := ioutil.ReadAll(reader)
+		bodies = append(bodies, string(body))
+		if encoding == "gzip" && strings.Contains(req.URL.Path, "/ids/") {
+			return stubResponse(415, `{"error": "Unsupported Media Type"}`), nil
+		}
+		return stubResponse(200, `[]`), nil
+	})
+	if _, err := e.PostJSON("universe/names", []int32{30000142}, Compressed()); err != nil {
+		t.Fatal(err)
+	}
+	if encodings[0] != "gzip" || bodies[0] != "[30000142]" {
+		t.Fatalf("Expected a gzipped body, got '%s' '%s'", encodings[0], bodies[0])
+	}
+	if _, err := e.PostJSON("universe/ids", []string{"Jita"}, Compressed()); err != nil {
+		t.Fatalf("Expected the uncompressed body to be sent after a 415, got %v", err)
+	}
+	if len(encodings) != 3 || encodings[2] != "" || bodies[2] != `["Jita"]` {
+		t.Fatalf("Expected the body to be sent again uncompressed, got %q %q", encodings, bodies)
+	}
+	if _, err := e.PostJSON("universe/names", []int32{30000142}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Post("universe/names", `[30000142]`); err != nil {
+		t.Fatal(err)
+	}
+	if encodings[3] != "" || encodings[4] != "" {
+		t.Fatalf("Expected bodies to be sent uncompressed without Compressed, got %q", encodings)
+	}
+}
+
+func TestAutoRefresh(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)}
+	refreshStatus := 200
+	var walletCalls int
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if req.URL.String() == TokenURL {
+			if refreshStatus != 200 {
+				return stubResponse(refreshStatus, `{"error": "invalid_grant"}`), nil
+			}
+			return stubResponse(200, `{"access_token": "new", "expires_in": 1200}`), nil
+		}
+		walletCalls++
+		if req.Header.Get("Authorization") != "Bearer new" {
+			return stubResponse(403, `{"error": "token is expired"}`), nil
+		}
+		return stubResponse(200, `1000.5`), nil
+	})
+	e.SetClock(clock)
+	e.AccessToken = "old"
+	e.RefreshToken = "refresh"
+
+	_, err := e.Get("characters/%d/wallet", 90000001)
+	var esiErr *ESIError
+	if !errors.As(err, &esiErr) || esiErr.StatusCode != 403 || walletCalls != 1 {
+		t.Fatalf("Expected the 403 without AutoRefresh, got %v after %d calls", err, walletCalls)
+	}
+
+	e.AutoRefresh = true
+	data, err := e.Get("characters/%d/wallet", 90000001)
+	if err != nil || data.Data().(float64) != 1000.5 {
+		t.Fatalf("Expected the call to be retried with the new token, got %v", err)
+	}
+	if e.AccessToken != "new" || !e.TokenExpiry.Equal(clock.now.Add(20*time.Minute)) || walletCalls != 3 {
+		t.Fatalf("Unexpected token state: '%s', %s, %d calls", e.AccessToken, e.TokenExpiry, walletCalls)
+	}
+
+	e.AccessToken = "old"
+	refreshStatus = 400
+	if _, err := e.Get("characters/%d/wallet/journal", 90000001); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("Expected the refresh error, got %v", err)
+	}
+}
+
+func TestGetWithETag(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)}
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		expires := clock.now.Add(time.Minute).Format(http.TimeFormat)
+		if req.Header.Get("If-None-Match") == `"a"` {
+			return stubResponse(304, "", "ETag", `"a"`, "Expires", expires), nil
+		}
+		return stubResponse(200, `{"players": 30000}`, "ETag", `"a"`, "Expires", expires), nil
+	})
+	e.SetClock(clock)
+	tests := []struct {
+		name      string
+		advance   time.Duration
+		fromCache bool
+		calls     int
+	}{
+		{"first call", 0, false, 1},
+		{"cached", 0, true, 1},
+		{"revalidated after expiring", 2 * time.Minute, false, 2},
+	}
+	for _, test := range tests {
+		clock.now = clock.now.Add(test.advance)
+		data, etag, fromCache, err := e.GetWithETag("status")
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		if data.Path("players").Data().(float64) != 30000 || etag != `"a"` || fromCache != test.fromCache || calls != test.calls {
+			t.Fatalf("%s: unexpected %s, %s, fromCache %t after %d calls", test.name, data, etag, fromCache, calls)
+		}
+	}
+}
+
+func TestGetIfNoneMatch(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			return stubResponse(304, ""), nil
+		}
+		return stubResponse(200, `{"players": 30000}`, "ETag", `"v1"`, "Expires", time.Now().UTC().Add(time.Hour).Format(http.TimeFormat)), nil
+	})
+	data, etag, notModified, err := e.GetIfNoneMatch("", "status")
+	if err != nil || notModified || etag != `"v1"` || data.Path("players").Data().(float64) != 30000 {
+		t.Fatalf("Unexpected response: '%s' '%s' %v %v", data, etag, notModified, err)
+	}
+	data, etag, notModified, err = e.GetIfNoneMatch(`"v1"`, "status")
+	if err != nil || !notModified || etag != `"v1"` || data != nil {
+		t.Fatalf("Expected not modified, got '%s' '%s' %v %v", data, etag, notModified, err)
+	}
+	if stats := e.Stats(); stats != (CacheStats{}) {
+		t.Fatalf("Expected the cache not to be used, got %+v", stats)
+	}
+	if _, ok := e.TimeUntilExpiry("status"); ok {
+		t.Fatal("Expected nothing to be cached")
+	}
+}
+
+func TestSetCompatibilityDate(t *testing.T) {
+	var dates []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		dates = append(dates, req.Header.Get(CompatibilityDateHeader))
+		return stubResponse(200, `{}`), nil
+	})
+	e.SetClock(&fakeClock{now: time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)})
+	for _, date := range []string{"2020-03-02", "01/01/2020", "2020-02-30", "yesterday"} {
+		if err := e.SetCompatibilityDate(date); err == nil {
+			t.Fatalf("Expected an error for '%s'", date)
+		}
+	}
+	if e.CompatibilityDate() != "" {
+		t.Fatalf("Expected invalid dates not to be set, got '%s'", e.CompatibilityDate())
+	}
+	if err := e.SetCompatibilityDate("2020-03-01"); err != nil {
+		t.Fatalf("Expected today's date to be allowed, got %s", err)
+	}
+	e.Get("status", NoCache())
+	if err := e.SetCompatibilityDate(""); err != nil {
+		t.Fatal(err)
+	}
+	e.Get("status", NoCache())
+	if len(dates) != 2 || dates[0] != "2020-03-01" || dates[1] != "" {
+		t.Fatalf("Expected the header only while the date was set, got %q", dates)
+	}
+}
+
+func TestDefaultUserAgentWarning(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		return stubResponse(200, `{}`), nil
+	})
+	logger := &recordingLogger{logs: make(map[string][]map[string]interface{})}
+	e.SetLogger(logger)
+	warning := "UserAgent is the default; set it to describe your app and how to contact you, as CCP may throttle generic user agents"
+	for i := 0; i < 2; i++ {
+		e.Get("status")
+	}
+	if len(logger.logs[warning]) != 1 {
+		t.Fatalf("Expected the warning once, got %d", len(logger.logs[warning]))
+	}
+
+	for _, quiet := range []func(e *ESI){
+		func(e *ESI) { e.QuietUserAgent = true },
+		func(e *ESI) { e.UserAgent = "My App (someone@example.com)" },
+	} {
+		e = newStubbedESI(func(req *http.Request) (*http.Response, error) {
+			return stubResponse(200, `{}`), nil
+		})
+		logger = &recordingLogger{logs: make(map[string][]map[string]interface{})}
+		e.SetLogger(logger)
+		quiet(e)
+		e.Get("status")
+		if len(logger.logs[warning]) != 0 {
+			t.Fatal("Expected no warning")
+		}
+	}
+}
+
+func TestTokenTTL(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)}
+	e := New("clientID", "clientSecret", "http://localhost/callback")
+	e.SetClock(clock)
+	if ttl := e.TokenTTL(); ttl != 0 {
+		t.Fatalf("Expected 0 for an unknown expiry, got %s", ttl)
+	}
+	e.TokenExpiry = clock.now.Add(20 * time.Minute)
+	clock.now = clock.now.Add(5 * time.Minute)
+	if ttl := e.TokenTTL(); ttl != 15*time.Minute {
+		t.Fatalf("Expected 15m, got %s", ttl)
+	}
+	clock.now = clock.now.Add(time.Hour)
+	if ttl := e.TokenTTL(); ttl != -45*time.Minute {
+		t.Fatalf("Expected -45m, got %s", ttl)
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	for _, name := range []string{EnvClientID, EnvSecret, EnvCallbackURL, EnvUserAgent} {
+		defer os.Setenv(name, os.Getenv(name))
+		os.Unsetenv(name)
+	}
+	os.Setenv(EnvClientID, "clientID")
+	_, err := NewFromEnv()
+	if err == nil || !strings.Contains(err.Error(), EnvSecret+", "+EnvCallbackURL) {
+		t.Fatalf("Expected the missing variables to be listed, got %v", err)
+	}
+
+	os.Setenv(EnvSecret, "secret")
+	os.Setenv(EnvCallbackURL, "http://localhost/callback")
+	os.Setenv(EnvUserAgent, "My App (someone@example.com)")
+	e, err := NewFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ClientID != "clientID" || e.ClientSecret != "secret" || e.ClientCallbackURL != "http://localhost/callback" || e.UserAgent != "My App (someone@example.com)" {
+		t.Fatalf("Unexpected client data: %+v", e)
+	}
+}
@@ -0,0 +1,132 @@
+package goesi
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// routeMetricsSamples is how many of the most recent call durations are kept per route
+// to work out the percentiles from
+const routeMetricsSamples = 1000
+
+// RouteStat is how calls to a route have gone, for RouteMetrics. The percentiles are of
+// the most recent calls, up to a thousand of them, and time each call until the response's
+// headers arrive.
+type RouteStat struct {
+	// Count is the number of calls made to the route
+	Count int64
+	// Errors is the number of those calls that couldn't be made, or got a 4xx or 5xx response
+	Errors int64
+	P50    time.Duration
+	P95    time.Duration
+	P99    time.Duration
+}
+
+// routeMetrics collects the RouteStats when ESI.CollectRouteMetrics is set
+type routeMetrics struct {
+	mu     sync.Mutex
+	routes map[string]*routeSamples
+}
+
+// routeSamples are the counts and recent call durations of a route
+type routeSamples struct {
+	count     int64
+	errors    int64
+	durations []time.Duration
+	// next is where the next duration goes once durations is full
+	next int
+}
+
+// record adds a call to the route's metrics
+func (m *routeMetrics) record(route string, duration time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.routes == nil {
+		m.routes = make(map[string]*routeSamples)
+	}
+	samples, ok := m.routes[route]
+	if !ok {
+		samples = &routeSamples{}
+		m.routes[route] = samples
+	}
+	samples.count++
+	if failed {
+		samples.errors++
+	}
+	if len(samples.durations) < routeMetricsSamples {
+		samples.durations = append(samples.durations, duration)
+		return
+	}
+	samples.durations[samples.next] = duration
+	samples.next = (samples.next + 1) % routeMetricsSamples
+}
+
+// stats returns the RouteStat of each route
+func (m *routeMetrics) stats() map[string]RouteStat {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := make(map[string]RouteStat, len(m.routes))
+	for route, samples := range m.routes {
+		sorted := append([]time.Duration(nil), samples.durations...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		stats[route] = RouteStat{
+			Count:  samples.count,
+			Errors: samples.errors,
+			P50:    percentile(sorted, 50),
+			P95:    percentile(sorted, 95),
+			P99:    percentile(sorted, 99),
+		}
+	}
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of the sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// metricsRoute returns the route of the URL with its ID segments replaced by "{id}", so that
+// calls for different characters, types, and so on are counted together under one route
+func metricsRoute(u string) string {
+	segments := strings.Split(routeOf(u), "/")
+	for i, segment := range segments {
+		if isID(segment) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// isID returns whether the path segment is a number, like a character ID
+func isID(segment string) bool {
+	if segment == "" {
+		return false
+	}
+	for _, r := range segment {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// RouteMetrics returns how calls to each route have gone since CollectRouteMetrics was set:
+// how many were made, how many failed, and how long they took. Routes are keyed with their
+// IDs replaced by "{id}", like "characters/{id}/wallet", so calls to a route for different
+// characters are counted together. Each retry of a call counts as a call of its own. The map
+// is empty if CollectRouteMetrics isn't set.
+func (e *ESI) RouteMetrics() map[string]RouteStat {
+	if e.metrics == nil {
+		return map[string]RouteStat{}
+	}
+	return e.metrics.stats()
+}
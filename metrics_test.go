@@ -0,0 +1,49 @@
+package goesi
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRouteMetrics(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)}
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if strings.Contains(req.URL.Path, "status") {
+			clock.now = clock.now.Add(time.Second)
+			return nil, errors.New("connection refused")
+		}
+		clock.now = clock.now.Add(time.Duration(calls) * time.Millisecond)
+		if calls == 100 {
+			return stubResponse(404, `{"error": "Character not found"}`), nil
+		}
+		return stubResponse(200, `{}`), nil
+	})
+	e.SetClock(clock)
+	e.BreakerThreshold = 0
+	e.Get("characters/90000001/wallet")
+	if metrics := e.RouteMetrics(); len(metrics) != 0 {
+		t.Fatalf("Expected no metrics until they're enabled, got %v", metrics)
+	}
+
+	e.CollectRouteMetrics = true
+	for id := 2; id <= 100; id++ {
+		e.Get("characters/%d/wallet", 90000000+id)
+	}
+	e.Get("status")
+	metrics := e.RouteMetrics()
+	wallet := metrics["characters/{id}/wallet"]
+	if wallet.Count != 99 || wallet.Errors != 1 {
+		t.Fatalf("Expected 99 calls with 1 error, got %+v", wallet)
+	}
+	if wallet.P50 != 51*time.Millisecond || wallet.P95 != 96*time.Millisecond || wallet.P99 != 100*time.Millisecond {
+		t.Fatalf("Unexpected percentiles: %+v", wallet)
+	}
+	if status := metrics["status"]; status.Count != 1 || status.Errors != 1 || status.P99 != time.Second {
+		t.Fatalf("Expected the failed call to be counted, got %+v", status)
+	}
+}
@@ -0,0 +1,74 @@
+package goesi
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Options configures the HTTP client used to talk to ESI.
+// Any field left as its zero value uses the default.
+type Options struct {
+	// MaxIdleConns is the maximum number of idle connections kept across all hosts
+	MaxIdleConns int
+	// MaxIdleConnsPerHost is the maximum number of idle connections kept to each host.
+	// As nearly every call goes to the one ESI host, this is the setting that limits
+	// how many concurrent calls can reuse a connection.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept before being closed
+	IdleConnTimeout time.Duration
+	// Dialer, if set, opens the connections to ESI, for full control over how they're made
+	Dialer *net.Dialer
+	// LocalAddr is the local IP address to make connections from, for hosts with
+	// several addresses where only one is allowed through a firewall
+	LocalAddr net.IP
+	// IPv4Only makes connections only over IPv4
+	IPv4Only bool
+}
+
+const (
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 20
+	defaultIdleConnTimeout     = 90 * time.Second
+)
+
+// newTransport creates the HTTP transport for the options
+func newTransport(opts Options) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = defaultMaxIdleConns
+	if opts.MaxIdleConns > 0 {
+		transport.MaxIdleConns = opts.MaxIdleConns
+	}
+	transport.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	if opts.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	transport.IdleConnTimeout = defaultIdleConnTimeout
+	if opts.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	if opts.Dialer != nil || opts.LocalAddr != nil || opts.IPv4Only {
+		transport.DialContext = dialContext(opts)
+	}
+	return transport
+}
+
+// dialContext returns the function that opens connections for the options' dialer settings
+func dialContext(opts Options) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	// the same settings as http.DefaultTransport's dialer
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if opts.Dialer != nil {
+		copied := *opts.Dialer
+		dialer = &copied
+	}
+	if opts.LocalAddr != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: opts.LocalAddr}
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if opts.IPv4Only && network == "tcp" {
+			network = "tcp4"
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
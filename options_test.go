@@ -0,0 +1,60 @@
+package goesi
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDialContext(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	dial := dialContext(Options{LocalAddr: net.ParseIP("127.0.0.1"), IPv4Only: true})
+	conn, err := dial(context.Background(), "tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	local := conn.LocalAddr().(*net.TCPAddr)
+	conn.Close()
+	if !local.IP.Equal(net.ParseIP("127.0.0.1")) {
+		t.Fatalf("Expected the connection to be from 127.0.0.1, got %s", local)
+	}
+
+	dial = dialContext(Options{IPv4Only: true})
+	if _, err := dial(context.Background(), "tcp", "[::1]:1"); err == nil || !strings.Contains(err.Error(), "tcp4") {
+		t.Fatalf("Expected an IPv4-only dial to an IPv6 address to fail, got %v", err)
+	}
+	if transport := newTransport(Options{}); transport.DialContext == nil {
+		t.Fatal("Expected the default transport's dialer to be kept")
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	transport := newTransport(Options{})
+	if transport.MaxIdleConns != defaultMaxIdleConns || transport.MaxIdleConnsPerHost != defaultMaxIdleConnsPerHost || transport.IdleConnTimeout != defaultIdleConnTimeout {
+		t.Fatalf("Expected the default pooling, got %d, %d, %s", transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+	transport = newTransport(Options{MaxIdleConns: 500, MaxIdleConnsPerHost: 50, IdleConnTimeout: time.Minute})
+	if transport.MaxIdleConns != 500 || transport.MaxIdleConnsPerHost != 50 || transport.IdleConnTimeout != time.Minute {
+		t.Fatalf("Expected the options' pooling, got %d, %d, %s", transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+	if http.DefaultTransport.(*http.Transport).MaxIdleConnsPerHost == 50 {
+		t.Fatal("Expected the default transport to be left alone")
+	}
+}
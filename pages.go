@@ -0,0 +1,236 @@
+package goesi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Jeffail/gabs"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// PagesHeader is the response header ESI uses to say how many pages a paginated route has
+const PagesHeader = "X-Pages"
+
+// GetAllPages fetches every page of a paginated route and returns the items from all
+// of them in one array. The first page is fetched to find out how many pages there are,
+// then the rest are fetched concurrently. Routes that aren't paginated return just
+// the first page.
+//
+// If the first page fails, its error is returned. If any of the other pages fail, the
+// items from the pages that succeeded are still returned, in page order, along with a
+// *PageError holding the error for each page that failed. Check for it before using the
+// items, as they're missing the failed pages' items.
+//
+// RequestOptions can be passed after the format args, as with Get. With WithDeadline, or
+// a context with a deadline, the pages all share the one deadline: once it passes, no more
+// pages are fetched and the pages that weren't fail with context.DeadlineExceeded.
+func (e *ESI) GetAllPages(path string, args ...interface{}) (*gabs.Container, error) {
+	args, opts := splitOptions(args)
+	opts, cancel := opts.withDeadline()
+	defer cancel()
+	return e.getAllPages(fmt.Sprintf(path, args...), opts.params, opts)
+}
+
+// GetAllPagesInto is like GetAllPages, but decodes the items from all of the pages into
+// a slice of T, for routes that return a JSON array of objects, like a character's assets
+// into a slice of a struct with their fields. If some of the pages fail, the items from
+// those that succeeded are still returned, along with the *PageError, as with GetAllPages.
+func GetAllPagesInto[T any](e *ESI, path string, args ...interface{}) ([]T, error) {
+	data, err := e.GetAllPages(path, args...)
+	var pageErr *PageError
+	if err != nil && !errors.As(err, &pageErr) {
+		return nil, err
+	}
+	items := []T{}
+	if decodeErr := decode(data, &items); decodeErr != nil {
+		e.log.Error("Error parsing pages", "path", path, "error", decodeErr)
+		return nil, decodeErr
+	}
+	return items, err
+}
+
+// pageChannelBuffer is how many pages PageChannel fetches ahead of the consumer
+const pageChannelBuffer = 4
+
+// PageResult is a page of a paginated route sent by PageChannel: its number, and either
+// its data, the JSON array of the page's items, or the error fetching it
+type PageResult struct {
+	Page int
+	Data *gabs.Container
+	Err  error
+}
+
+// PageChannel fetches the pages of a paginated route one at a time, in order, and sends
+// them on the channel, for routes with too many pages to hold all at once, like a region's
+// market orders. It fetches up to a few pages ahead of the consumer, then waits for the
+// consumer to catch up. The channel is closed after the last page, or once the context
+// is cancelled, so the consumer can simply range over it.
+//
+// If the first page fails, its error is sent and the channel is closed, as the number of
+// pages isn't known. If any of the other pages fail, their errors are sent in their place
+// and the rest of the pages are still fetched.
+//
+// RequestOptions can be passed after the format args, as with Get; they apply to each page.
+// The ctx passed to PageChannel is used instead of one from WithContext.
+func (e *ESI) PageChannel(ctx context.Context, path string, args ...interface{}) <-chan PageResult {
+	args, opts := splitOptions(args)
+	opts.ctx = ctx
+	path = fmt.Sprintf(path, args...)
+	results := make(chan PageResult, pageChannelBuffer)
+	go func() {
+		defer close(results)
+		opts, cancel := opts.withDeadline()
+		defer cancel()
+		ctx := opts.ctx
+		for page, pages := 1, 1; page <= pages; page++ {
+			data, header, err := e.getWith(e.pageURL(path, opts.params, page), opts)
+			if err == nil {
+				_, err = pageItems(data)
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			result := PageResult{Page: page, Err: err}
+			if err == nil {
+				result.Data = data
+				if page == 1 {
+					pages = pageCount(header)
+				}
+			}
+			select {
+			case results <- result:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil && page == 1 {
+				return
+			}
+		}
+	}()
+	return results
+}
+
+// getAllPages fetches every page of the path with the params and returns the items from all of them
+func (e *ESI) getAllPages(path string, params url.Values, opts requestOptions) (*gabs.Container, error) {
+	first, header, err := e.getWith(e.pageURL(path, params, 1), opts)
+	if err != nil {
+		return nil, err
+	}
+	items, err := pageItems(first)
+	if err != nil {
+		return nil, err
+	}
+	pages := pageCount(header)
+	if pages > 1 {
+		e.log.Debug("Fetching remaining pages", "path", path, "pages", pages)
+		urls := make([]string, 0, pages-1)
+		for page := 2; page <= pages; page++ {
+			urls = append(urls, e.pageURL(path, params, page))
+		}
+		results, _, errs := e.getURLs(urls, opts)
+		failures := make(map[int]error)
+		for i, result := range results {
+			if errs[i] == nil {
+				var more []interface{}
+				if more, errs[i] = pageItems(result); errs[i] == nil {
+					items = append(items, more...)
+					continue
+				}
+			}
+			failures[i+2] = errs[i]
+		}
+		if len(failures) > 0 {
+			e.log.Warn("Some pages failed", "path", path, "pages", pages, "failed", len(failures))
+			data, err := gabs.Consume(items)
+			if err != nil {
+				return nil, err
+			}
+			return data, &PageError{Errors: failures, Pages: pages}
+		}
+	}
+	return gabs.Consume(items)
+}
+
+// GetAllCursor fetches every batch of a route that's paginated with a cursor instead of
+// X-Pages, like a character's mail, and returns the items from all of them in one array.
+// The first batch is fetched without the cursor param. extract is given each batch, a JSON
+// array, and returns the cursor to ask for the next batch with, like the ID of the oldest
+// item in it, or that the batch is empty, which ends the fetching. Fetching also ends when
+// the next cursor is "" or the same as the last one. Batches are fetched one at a time, as
+// each depends on the last. If any batch fails, its error is returned.
+//
+// RequestOptions can be passed after the format args, as with Get; a deadline is shared
+// between all of the batches.
+func (e *ESI) GetAllCursor(path, cursorParam string, extract func(*gabs.Container) (nextCursor string, empty bool), args ...interface{}) (*gabs.Container, error) {
+	args, opts := splitOptions(args)
+	opts, cancel := opts.withDeadline()
+	defer cancel()
+	path = fmt.Sprintf(path, args...)
+	var items []interface{}
+	cursor := ""
+	for {
+		params := url.Values{}
+		for key, value := range opts.params {
+			params[key] = value
+		}
+		if cursor != "" {
+			params.Set(cursorParam, cursor)
+		}
+		batch, _, err := e.getWith(e.buildURL(withQuery(path, params)), opts)
+		if err != nil {
+			return nil, err
+		}
+		more, err := pageItems(batch)
+		if err != nil {
+			return nil, err
+		}
+		next, empty := extract(batch)
+		if empty {
+			break
+		}
+		items = append(items, more...)
+		if next == "" || next == cursor {
+			break
+		}
+		cursor = next
+	}
+	if items == nil {
+		items = []interface{}{}
+	}
+	return gabs.Consume(items)
+}
+
+// pageURL returns the URL of a page of the path with the params, along with any
+// query params already in the path
+func (e *ESI) pageURL(path string, params url.Values, page int) string {
+	path, query := splitQuery(path)
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		values = url.Values{}
+	}
+	for key, value := range params {
+		values[key] = value
+	}
+	values.Set("page", strconv.Itoa(page))
+	return e.buildURL(path) + "?" + values.Encode()
+}
+
+// pageCount returns the number of pages from the response headers, or 1 if they don't say
+func pageCount(h http.Header) int {
+	pages, err := strconv.Atoi(h.Get(PagesHeader))
+	if err != nil || pages < 1 {
+		return 1
+	}
+	return pages
+}
+
+// pageItems returns the items in a page, which is a JSON array
+func pageItems(page *gabs.Container) ([]interface{}, error) {
+	items, ok := page.Data().([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Expected a page to be an array, got '%s'", page)
+	}
+	return items, nil
+}
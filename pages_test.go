@@ -0,0 +1,241 @@
+package goesi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Jeffail/gabs"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGetAllPages(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Query().Get("page") {
+		case "1":
+			return stubResponse(200, `[{"item_id": 1, "location_flag": "Hangar"}, {"item_id": 2, "location_flag": "Cargo"}]`, PagesHeader, "2"), nil
+		case "2":
+			return stubResponse(200, `[{"item_id": 3, "location_flag": "Hangar"}]`, PagesHeader, "2"), nil
+		}
+		t.Fatalf("Unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	assets, err := e.CharacterAssets(90000001, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(assets) != 3 || assets[2].ItemID != 3 {
+		t.Fatalf("Expected the items from both pages, got %+v", assets)
+	}
+	hangar, err := e.CharacterAssets(90000001, []string{"Hangar"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(hangar) != 2 || hangar[0].ItemID != 1 || hangar[1].ItemID != 3 {
+		t.Fatalf("Expected only the hangar items, got %+v", hangar)
+	}
+}
+
+func TestGetAllPagesPartialFailure(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Query().Get("page") {
+		case "1":
+			return stubResponse(200, `[1, 2]`, PagesHeader, "4"), nil
+		case "3":
+			return stubResponse(200, `[5]`, PagesHeader, "4"), nil
+		}
+		return stubResponse(502, `{"error": "bad gateway"}`), nil
+	})
+	e.BreakerThreshold = 0
+	data, err := e.GetAllPages("characters/%d/assets", 90000001)
+	var pageErr *PageError
+	if !errors.As(err, &pageErr) {
+		t.Fatalf("Expected a *PageError, got %v", err)
+	}
+	if failed := pageErr.FailedPages(); len(failed) != 2 || failed[0] != 2 || failed[1] != 4 || pageErr.Pages != 4 {
+		t.Fatalf("Expected pages 2 and 4 of 4 to fail, got %v of %d", failed, pageErr.Pages)
+	}
+	var esiErr *ESIError
+	if !errors.As(err, &esiErr) || esiErr.StatusCode != 502 {
+		t.Fatalf("Expected the page's *ESIError to be wrapped, got %v", err)
+	}
+	if items, _ := data.Children(); len(items) != 3 {
+		t.Fatalf("Expected the items from the pages that succeeded, got %s", data)
+	}
+	if _, err := e.CharacterAssets(90000001, nil); !errors.As(err, &pageErr) {
+		t.Fatalf("Expected CharacterAssets to fail with the *PageError, got %v", err)
+	}
+}
+
+func TestGetAllPagesInto(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "blueprints") {
+			return stubResponse(200, `[]`), nil
+		}
+		switch req.URL.Query().Get("page") {
+		case "1":
+			return stubResponse(200, `[{"order_id": 1, "price": 5.5}, {"order_id": 2, "price": 6}]`, PagesHeader, "3"), nil
+		case "2":
+			return stubResponse(200, `[{"order_id": 3, "price": 7}]`, PagesHeader, "3"), nil
+		}
+		return stubResponse(502, `{"error": "bad gateway"}`), nil
+	})
+	e.BreakerThreshold = 0
+	type order struct {
+		OrderID int64   `json:"order_id"`
+		Price   float64 `json:"price"`
+	}
+	orders, err := GetAllPagesInto[order](e, "markets/%d/orders", 10000002)
+	var pageErr *PageError
+	if !errors.As(err, &pageErr) || len(pageErr.Errors) != 1 {
+		t.Fatalf("Expected a *PageError for page 3, got %v", err)
+	}
+	if len(orders) != 3 || orders[0].Price != 5.5 || orders[2].OrderID != 3 {
+		t.Fatalf("Expected the orders from the pages that succeeded, got %+v", orders)
+	}
+
+	empty, err := GetAllPagesInto[order](e, "characters/%d/blueprints", 90000001)
+	if err != nil || empty == nil || len(empty) != 0 {
+		t.Fatalf("Expected an empty slice, got %v and %v", empty, err)
+	}
+	if _, err := GetAllPagesInto[string](e, "markets/%d/orders", 10000002); err == nil || errors.As(err, &pageErr) {
+		t.Fatalf("Expected an error decoding orders as strings, got %v", err)
+	}
+}
+
+func TestGetAllPagesWithQuery(t *testing.T) {
+	var queries []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		queries = append(queries, req.URL.RawQuery)
+		if !strings.HasSuffix(req.URL.Path, "/markets/10000002/orders/") {
+			t.Fatalf("Unexpected request to %s", req.URL)
+		}
+		return stubResponse(200, `[{"order_id": 1}]`, PagesHeader, "2"), nil
+	})
+	if _, err := e.GetAllPages("markets/%d/orders?order_type=sell", 10000002); err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 2 || queries[0] != "order_type=sell&page=1" || queries[1] != "order_type=sell&page=2" {
+		t.Fatalf("Expected the path's query to be merged with the page, got %q", queries)
+	}
+}
+
+func TestPageChannel(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		page := req.URL.Query().Get("page")
+		if page == "3" {
+			return stubResponse(404, `{"error": "page not found"}`), nil
+		}
+		return stubResponse(200, `[`+page+`]`, PagesHeader, "10"), nil
+	})
+	pages := e.PageChannel(context.Background(), "markets/%d/orders", 10000002)
+
+	// with no one receiving, it only fetches as far ahead as the buffer allows
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	ahead := calls
+	mu.Unlock()
+	if ahead != pageChannelBuffer+1 {
+		t.Fatalf("Expected %d pages to be fetched ahead, got %d", pageChannelBuffer+1, ahead)
+	}
+
+	var received []int
+	for result := range pages {
+		received = append(received, result.Page)
+		if result.Page == 3 {
+			if result.Err == nil {
+				t.Fatal("Expected page 3's error")
+			}
+			continue
+		}
+		if result.Err != nil {
+			t.Fatalf("Page %d: unexpected error: %s", result.Page, result.Err)
+		}
+		if items, _ := result.Data.Children(); len(items) != 1 || items[0].Data().(float64) != float64(result.Page) {
+			t.Fatalf("Page %d: unexpected data %s", result.Page, result.Data)
+		}
+	}
+	if len(received) != 10 || received[0] != 1 || received[9] != 10 {
+		t.Fatalf("Expected all 10 pages in order, got %v", received)
+	}
+}
+
+func TestPageChannelCancel(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		return stubResponse(200, `[1]`, PagesHeader, "1000"), nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	pages := e.PageChannel(ctx, "markets/%d/orders", 10000002, NoCache())
+	if first := <-pages; first.Page != 1 || first.Err != nil {
+		t.Fatalf("Unexpected first page: %+v", first)
+	}
+	cancel()
+	received := 1
+	for range pages {
+		received++
+	}
+	if received > pageChannelBuffer+2 {
+		t.Fatalf("Expected the channel to be closed soon after cancelling, got %d pages", received)
+	}
+
+	e = newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		return stubResponse(500, `{"error": "unavailable"}`), nil
+	})
+	e.BreakerThreshold = 0
+	var results []PageResult
+	for result := range e.PageChannel(context.Background(), "markets/%d/orders", 10000002) {
+		results = append(results, result)
+	}
+	if len(results) != 1 || results[0].Err == nil {
+		t.Fatalf("Expected only the first page's error, got %+v", results)
+	}
+}
+
+func TestGetAllCursor(t *testing.T) {
+	var queries []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		queries = append(queries, req.URL.RawQuery)
+		switch req.URL.Query().Get("before") {
+		case "":
+			return stubResponse(200, `[{"id": 30}, {"id": 20}]`), nil
+		case "20":
+			return stubResponse(200, `[{"id": 10}]`), nil
+		}
+		return stubResponse(200, `[]`), nil
+	})
+	extract := func(batch *gabs.Container) (string, bool) {
+		children, _ := batch.Children()
+		if len(children) == 0 {
+			return "", true
+		}
+		return fmt.Sprint(children[len(children)-1].Path("id").Data()), false
+	}
+	data, err := e.GetAllCursor("characters/%d/journal", "before", extract, 90000001, WithParams(url.Values{"kind": {"all"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := data.Children(); len(items) != 3 || items[2].Path("id").Data().(float64) != 10 {
+		t.Fatalf("Expected the items from every batch, got %s", data)
+	}
+	if strings.Join(queries, " ") != "kind=all before=20&kind=all before=10&kind=all" {
+		t.Fatalf("Expected each batch to be asked for with the cursor and the params, got %q", queries)
+	}
+
+	calls := 0
+	e = newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return stubResponse(200, `[{"id": 1}]`), nil
+	})
+	if _, err := e.GetAllCursor("characters/%d/journal", "before", extract, 90000001); err != nil || calls != 2 {
+		t.Fatalf("Expected fetching to stop when the cursor doesn't change, got %d calls and %v", calls, err)
+	}
+}
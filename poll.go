@@ -0,0 +1,143 @@
+package goesi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/Jeffail/gabs"
+	"time"
+)
+
+// pollMinInterval is the shortest time polling waits between checks, for responses
+// without an expiry or that had already expired when they were fetched
+const pollMinInterval = 5 * time.Second
+
+// pollMaxBackoff is the longest Subscribe waits between checks after failed checks,
+// with DefaultBackoff
+const pollMaxBackoff = 5 * time.Minute
+
+// Poll checks the path until the context is cancelled, calling onChange with the
+// response the first time and whenever it changes after that. Between checks it waits
+// until the cached response expires, and each check is made conditional on the
+// response's ETag, so unchanged data costs ESI as little as possible. Changes are
+// detected by the ETag, or by the body for responses without one. If few calls may
+// error before ESI's error limit is reached, it waits for the error limit to reset.
+//
+// Poll returns the context's error once it's cancelled, or the error from a failed check.
+// Cancelling the context also cancels a check that's in flight.
+//
+// RequestOptions can be passed after the format args, as with Get; they apply to each
+// check. The ctx passed to Poll is used instead of one from WithContext, and polling
+// stops at the deadline from WithDeadline.
+func (e *ESI) Poll(ctx context.Context, path string, onChange func(*gabs.Container), args ...interface{}) error {
+	url, opts := e.optionsURL(path, args)
+	opts.ctx = ctx
+	opts, cancel := opts.withDeadline()
+	defer cancel()
+	return e.poll(url, opts, onChange, func(err error) error {
+		return err
+	})
+}
+
+// Subscribe is like Poll, but sends each changed response on the first channel instead
+// of calling a function, and carries on after failed checks, sending their errors on the
+// second channel. After a failed check it waits longer before the next, as long as ESI.Backoff
+// says (by default, doubling up to five minutes), or as long as ESI asked for if the call was
+// rate limited. Both channels are closed once
+// the context is cancelled. The caller must keep receiving from both channels.
+// RequestOptions can be passed after the format args, as with Poll.
+func (e *ESI) Subscribe(ctx context.Context, path string, args ...interface{}) (<-chan *gabs.Container, <-chan error) {
+	url, opts := e.optionsURL(path, args)
+	opts.ctx = ctx
+	values := make(chan *gabs.Container)
+	errs := make(chan error)
+	go func() {
+		defer close(values)
+		defer close(errs)
+		opts, cancel := opts.withDeadline()
+		defer cancel()
+		ctx := opts.ctx
+		e.poll(url, opts, func(json *gabs.Container) {
+			select {
+			case values <- json:
+			case <-ctx.Done():
+			}
+		}, func(err error) error {
+			select {
+			case errs <- err:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	return values, errs
+}
+
+// poll checks the URL with the options until their context is cancelled, calling onChange
+// whenever the response changes. When a check fails, onError is called with the error; if it
+// returns an error, polling stops with it, otherwise the next check is made after a backoff.
+func (e *ESI) poll(url string, opts requestOptions, onChange func(*gabs.Container), onError func(error) error) error {
+	ctx := opts.ctx
+	var last *gabs.Container
+	var lastETag string
+	failures := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var wait time.Duration
+		json, header, err := e.getWith(url, opts)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err != nil {
+			e.log.Warn("Error polling ESI", "url", url, "error", err)
+			if err := onError(err); err != nil {
+				return err
+			}
+			failures++
+			wait = pollBackoff(e.backoff(), failures, err)
+		} else {
+			failures = 0
+			etag := header.Get("ETag")
+			if last == nil || changed(last, json, lastETag, etag) {
+				onChange(json)
+			}
+			last, lastETag = json, etag
+			wait = pollMinInterval
+			if expires, ok := e.cache.expires(e.cacheKey("GET", url)); ok {
+				if untilExpiry := expires.Sub(e.now()); untilExpiry > wait {
+					wait = untilExpiry
+				}
+			}
+		}
+		if untilReset := e.errorLimit.wait(e.now()); untilReset > wait {
+			e.log.Info("ESI error limit is low; waiting for it to reset", "url", url, "wait", untilReset)
+			wait = untilReset
+		}
+		if err := e.sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// pollBackoff returns how long to wait after a number of failed checks in a row, as long
+// as the backoff says, or as long as ESI asked for if the call was rate limited
+func pollBackoff(backoff Backoff, failures int, err error) time.Duration {
+	wait := backoff.Next(failures)
+	var esiErr *ESIError
+	if errors.As(err, &esiErr) && esiErr.RetryAfter > wait {
+		wait = esiErr.RetryAfter
+	}
+	return wait
+}
+
+// changed returns whether a polled response is different from the previous one,
+// by the ETags if both have one, otherwise by the bodies
+func changed(previous, current *gabs.Container, previousETag, currentETag string) bool {
+	if previousETag != "" && currentETag != "" {
+		return previousETag != currentETag
+	}
+	return !bytes.Equal(previous.Bytes(), current.Bytes())
+}
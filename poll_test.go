@@ -0,0 +1,165 @@
+package goesi
+
+import (
+	"context"
+	"errors"
+	"github.com/Jeffail/gabs"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPoll(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)}
+	expired := clock.now.Add(-time.Hour).Format(http.TimeFormat)
+
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		switch calls {
+		case 1:
+			return stubResponse(200, `{"count": 1}`, "ETag", `"a"`, "Expires", expired), nil
+		case 2:
+			if req.Header.Get("If-None-Match") != `"a"` {
+				t.Fatalf("Expected a conditional request, got If-None-Match '%s'", req.Header.Get("If-None-Match"))
+			}
+			return stubResponse(304, "", "ETag", `"a"`, "Expires", expired), nil
+		default:
+			return stubResponse(200, `{"count": 2}`, "ETag", `"b"`, "Expires", expired), nil
+		}
+	})
+	e.SetClock(clock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var changes []float64
+	err := e.Poll(ctx, "characters/%d/wallet", func(data *gabs.Container) {
+		changes = append(changes, data.Path("count").Data().(float64))
+		if len(changes) == 2 {
+			cancel()
+		}
+	}, 90000001)
+	if err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if len(changes) != 2 || changes[0] != 1 || changes[1] != 2 {
+		t.Fatalf("Expected onChange for each new ETag, got %v", changes)
+	}
+	if calls != 3 {
+		t.Fatalf("Expected 3 calls, got %d", calls)
+	}
+	if len(clock.waits) < 2 || clock.waits[0] != pollMinInterval {
+		t.Fatalf("Expected to wait on the clock between checks, got waits %v", clock.waits)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC), blockAt: 4}
+	expired := clock.now.Add(-time.Hour).Format(http.TimeFormat)
+
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		switch calls {
+		case 1:
+			return stubResponse(200, `{"members": 1}`, "ETag", `"a"`, "Expires", expired), nil
+		case 2:
+			return stubResponse(503, `{"error": "unavailable"}`), nil
+		case 3:
+			return stubResponse(304, "", "ETag", `"a"`, "Expires", expired), nil
+		default:
+			return stubResponse(200, `{"members": 2}`, "ETag", `"b"`, "Expires", expired,
+				ErrorLimitRemainHeader, "5", ErrorLimitResetHeader, "30"), nil
+		}
+	})
+	e.SetClock(clock)
+	e.Backoff = ExponentialBackoff{Min: pollMinInterval, Max: pollMaxBackoff}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	values, errs := e.Subscribe(ctx, "fleets/%d/members", 1234)
+	if first := <-values; first.Path("members").Data().(float64) != 1 {
+		t.Fatalf("Unexpected first value: %s", first)
+	}
+	var esiErr *ESIError
+	if err := <-errs; !errors.As(err, &esiErr) || esiErr.StatusCode != 503 {
+		t.Fatalf("Expected the failed check's error, got %v", err)
+	}
+	if second := <-values; second.Path("members").Data().(float64) != 2 {
+		t.Fatalf("Unexpected second value: %s", second)
+	}
+	cancel()
+	for range values {
+	}
+	for range errs {
+	}
+
+	expected := []time.Duration{pollMinInterval, pollMinInterval, pollMinInterval, 30 * time.Second}
+	waits := clock.waits
+	if len(waits) != len(expected) {
+		t.Fatalf("Expected waits %v, got %v", expected, waits)
+	}
+	for i := range expected {
+		if waits[i] != expected[i] {
+			t.Fatalf("Expected waits %v, got %v", expected, waits)
+		}
+	}
+}
+
+func TestPollBackoff(t *testing.T) {
+	exponential := ExponentialBackoff{Min: pollMinInterval, Max: pollMaxBackoff}
+	if got := pollBackoff(exponential, 3, errors.New("failed")); got != 4*pollMinInterval {
+		t.Fatalf("Expected the backoff to double each time, got %s", got)
+	}
+	if got := pollBackoff(exponential, 40, errors.New("failed")); got != pollMaxBackoff {
+		t.Fatalf("Expected the backoff to be capped, got %s", got)
+	}
+	if got := pollBackoff(exponential, 1, &ESIError{StatusCode: 420, RetryAfter: time.Minute}); got != time.Minute {
+		t.Fatalf("Expected the backoff to honor RetryAfter, got %s", got)
+	}
+	constant := BackoffFunc(func(int) time.Duration { return time.Second })
+	if got := pollBackoff(constant, 5, errors.New("failed")); got != time.Second {
+		t.Fatalf("Expected the custom backoff to be used, got %s", got)
+	}
+}
+
+func TestExponentialBackoffJitter(t *testing.T) {
+	b := ExponentialBackoff{Min: time.Second, Max: time.Minute, Jitter: 0.5}
+	varied := false
+	for i := 0; i < 100; i++ {
+		got := b.Next(4)
+		if got < 4*time.Second || got > 8*time.Second {
+			t.Fatalf("Expected a wait from 4s to 8s, got %s", got)
+		}
+		if got != 8*time.Second {
+			varied = true
+		}
+	}
+	if !varied {
+		t.Fatal("Expected the waits to be random")
+	}
+	if got := b.Next(100); got < 30*time.Second || got > time.Minute {
+		t.Fatalf("Expected the wait to be capped before the jitter, got %s", got)
+	}
+}
+
+func TestPollCancelsCheckInFlight(t *testing.T) {
+	started := make(chan struct{})
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		close(started)
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- e.Poll(ctx, "status", func(*gabs.Container) {
+			t.Error("Unexpected change")
+		})
+	}()
+	<-started
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
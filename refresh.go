@@ -0,0 +1,64 @@
+package goesi
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// autoRefreshMargin is how long before the access token expires that StartAutoRefresh
+// refreshes it, so that calls made just before the refresh still have a valid token
+const autoRefreshMargin = time.Minute
+
+// StartAutoRefresh starts refreshing the access token in the background shortly before it
+// expires, each time, until the context is cancelled. This keeps the token valid for workers
+// that go long stretches between calls, so one isn't made with an expired token when they
+// finally need ESI. If the token's expiry isn't known, it's refreshed straight away to find out.
+//
+// A refresh that fails is tried again after a backoff, per ESI.Backoff. If the SSO rejects the
+// refresh token, which will never work again, refreshing stops and the error is logged.
+// Refreshes share a lock with those made by AutoRefresh.
+func (e *ESI) StartAutoRefresh(ctx context.Context) {
+	go e.autoRefresh(ctx)
+}
+
+// autoRefresh refreshes the access token before it expires until the context is cancelled,
+// returning the context's error, or the error that stopped it
+func (e *ESI) autoRefresh(ctx context.Context) error {
+	failures := 0
+	for {
+		wait := time.Duration(0)
+		if failures > 0 {
+			wait = e.backoff().Next(failures)
+		} else if ttl := e.TokenTTL(); ttl > autoRefreshMargin {
+			wait = ttl - autoRefreshMargin
+		}
+		if err := e.sleep(ctx, wait); err != nil {
+			return err
+		}
+		err := e.refreshLocked()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		switch {
+		case errors.Is(err, ErrRefreshTokenInvalid) || errors.Is(err, ErrMissingRefreshToken):
+			e.log.Error("Cannot refresh the access token; stopping automatic refreshes", "error", err)
+			return err
+		case err != nil:
+			failures++
+			e.log.Warn("Error refreshing the access token; trying again", "error", err, "attempt", failures)
+		default:
+			failures = 0
+			e.log.Debug("Refreshed the access token", "expiry", e.TokenExpiry)
+		}
+	}
+}
+
+// refreshLocked refreshes the access token, holding the lock shared with AutoRefresh's refreshes
+func (e *ESI) refreshLocked() error {
+	if e.tokenMu != nil {
+		e.tokenMu.Lock()
+		defer e.tokenMu.Unlock()
+	}
+	return e.RefreshAccessToken()
+}
@@ -0,0 +1,60 @@
+package goesi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStartAutoRefresh(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		switch calls {
+		case 2:
+			return stubResponse(503, `{"error": "temporarily_unavailable"}`), nil
+		case 4:
+			cancel()
+		}
+		return stubResponse(200, `{"access_token": "token`+string(rune('0'+calls))+`", "expires_in": 1200}`), nil
+	})
+	e.SetClock(clock)
+	e.Backoff = ExponentialBackoff{Min: 10 * time.Second, Max: time.Minute}
+	e.RefreshToken = "refresh"
+
+	if err := e.autoRefresh(ctx); err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	// the expiry isn't known at first, then it's refreshed a minute before it expires,
+	// and a failed refresh is tried again after the backoff
+	expected := []time.Duration{0, 19 * time.Minute, 10 * time.Second, 19 * time.Minute}
+	if len(clock.waits) != len(expected) {
+		t.Fatalf("Expected waits %v, got %v", expected, clock.waits)
+	}
+	for i := range expected {
+		if clock.waits[i] != expected[i] {
+			t.Fatalf("Expected waits %v, got %v", expected, clock.waits)
+		}
+	}
+	if e.AccessToken != "token4" {
+		t.Fatalf("Expected the last refreshed token, got '%s'", e.AccessToken)
+	}
+}
+
+func TestStartAutoRefreshStopsForInvalidToken(t *testing.T) {
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return stubResponse(400, `{"error": "invalid_grant", "error_description": "Invalid refresh token"}`), nil
+	})
+	e.SetClock(&fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)})
+	e.RefreshToken = "refresh"
+	if err := e.autoRefresh(context.Background()); !errors.Is(err, ErrRefreshTokenInvalid) || calls != 1 {
+		t.Fatalf("Expected to stop after one call with ErrRefreshTokenInvalid, got %d calls and %v", calls, err)
+	}
+}
@@ -0,0 +1,187 @@
+package goesi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// A RequestOption changes how a single call is made. Pass them to Get after the
+// format args, like esi.Get("characters/%d/wallet", id, goesi.NoCache()).
+type RequestOption func(*requestOptions)
+
+// requestOptions are the settings for a single call
+type requestOptions struct {
+	noCache bool
+	public  bool
+	ctx     context.Context
+	// deadline, if set, is when the whole call has to be done by, retries and all
+	deadline time.Time
+	params   url.Values
+	headers  http.Header
+	// compress gzips the request body, for PostJSON
+	compress bool
+}
+
+// NoCache makes the call go to ESI even if there's a cached response, without
+// making it conditional on the cached response's ETag. The new response still
+// replaces the cached one.
+func NoCache() RequestOption {
+	return func(o *requestOptions) {
+		o.noCache = true
+	}
+}
+
+// WithContext makes the call with the context, so that the call is cancelled when
+// the context is, as well as when Shutdown is called
+func WithContext(ctx context.Context) RequestOption {
+	return func(o *requestOptions) {
+		o.ctx = ctx
+	}
+}
+
+// WithDeadline gives the call a deadline. Calls that make several requests, like
+// GetAllPages and GetMany, share the one deadline between all of their requests, and
+// rate limited requests aren't retried if the wait would take them past it.
+func WithDeadline(deadline time.Time) RequestOption {
+	return func(o *requestOptions) {
+		o.deadline = deadline
+	}
+}
+
+// WithParams adds the params to the URL as its query string, like GetWithParams
+func WithParams(params url.Values) RequestOption {
+	return func(o *requestOptions) {
+		if o.params == nil {
+			o.params = url.Values{}
+		}
+		for key, values := range params {
+			o.params[key] = append(o.params[key], values...)
+		}
+	}
+}
+
+// WithHeaders sends the headers with the call, replacing the standard headers of the
+// same name. The cache doesn't take headers into account, so if they change the
+// response, like Accept-Language does, use NoCache as well.
+func WithHeaders(headers map[string]string) RequestOption {
+	return func(o *requestOptions) {
+		if o.headers == nil {
+			o.headers = http.Header{}
+		}
+		for key, value := range headers {
+			o.headers.Set(key, value)
+		}
+	}
+}
+
+// WithAccept asks ESI for the response in the content type, like "text/csv", in place of
+// JSON. Get and the other methods that parse the response as JSON fail with a
+// *ContentTypeError for anything else, so use it with GetRaw or Do.
+func WithAccept(contentType string) RequestOption {
+	return WithHeaders(map[string]string{"Accept": contentType})
+}
+
+// Compressed gzips the body of a PostJSON call and sends it with Content-Encoding: gzip,
+// which saves bandwidth for large bodies. Not every route accepts a compressed body; if
+// one responds 415 Unsupported Media Type, the body is sent again uncompressed.
+func Compressed() RequestOption {
+	return func(o *requestOptions) {
+		o.compress = true
+	}
+}
+
+// newRequestOptions applies the options
+func newRequestOptions(options []RequestOption) requestOptions {
+	var opts requestOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	return opts
+}
+
+// withDeadline returns the options with a context that's done at the deadline, if there's
+// one. The returned function releases the context once the call is done.
+func (o requestOptions) withDeadline() (requestOptions, context.CancelFunc) {
+	if o.deadline.IsZero() {
+		return o, func() {}
+	}
+	base := o.ctx
+	if base == nil {
+		base = context.Background()
+	}
+	ctx, cancel := context.WithDeadline(base, o.deadline)
+	o.ctx = ctx
+	return o, cancel
+}
+
+// splitOptions separates the RequestOptions from the format args passed to Get
+func splitOptions(args []interface{}) ([]interface{}, requestOptions) {
+	var opts requestOptions
+	formatArgs := args[:0:0]
+	for _, arg := range args {
+		if option, ok := arg.(RequestOption); ok {
+			option(&opts)
+			continue
+		}
+		formatArgs = append(formatArgs, arg)
+	}
+	return formatArgs, opts
+}
+
+// optionsURL separates the RequestOptions from the args, and returns the full URL of the
+// path formatted with the rest of the args and with the options' params, and the options
+func (e *ESI) optionsURL(path string, args []interface{}) (string, requestOptions) {
+	args, opts := splitOptions(args)
+	return e.buildURL(withQuery(fmt.Sprintf(path, args...), opts.params)), opts
+}
+
+// withQuery returns the path with the params added to its query string
+func withQuery(path string, params url.Values) string {
+	if len(params) == 0 {
+		return path
+	}
+	if strings.Contains(path, "?") {
+		return path + "&" + params.Encode()
+	}
+	return path + "?" + params.Encode()
+}
+
+// apply sets the options' context and headers on the request. The returned
+// function must be called once the response has been read.
+func (o requestOptions) apply(req *http.Request) (*http.Request, func()) {
+	o.setHeaders(req.Header)
+	if o.ctx == nil {
+		return req, func() {}
+	}
+	// cancel the call when either the option's context or the struct's context is done
+	ctx, cancel := context.WithCancel(o.ctx)
+	stop := context.AfterFunc(req.Context(), cancel)
+	return req.WithContext(ctx), func() {
+		stop()
+		cancel()
+	}
+}
+
+// setHeaders sets the options' headers, replacing any of the same name
+func (o requestOptions) setHeaders(h http.Header) {
+	for key, values := range o.headers {
+		h[key] = values
+	}
+}
+
+// closeFunc is a response body that calls done once it's closed
+type closeFunc struct {
+	io.ReadCloser
+	done func()
+}
+
+func (c closeFunc) Close() error {
+	err := c.ReadCloser.Close()
+	c.done()
+	return err
+}
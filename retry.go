@@ -0,0 +1,70 @@
+package goesi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultMaxRetries is how many times a rate limited call is retried by default
+	defaultMaxRetries = 2
+	// maxRetryWait is the longest a call waits to be retried; calls that would have to
+	// wait longer fail straight away, with the wait in the ESIError's RetryAfter
+	maxRetryWait = time.Minute
+	// defaultRetryAfter is how long to wait when a rate limited response doesn't say
+	defaultRetryAfter = time.Second
+)
+
+// IsRateLimited returns whether the error is from ESI rate limiting a call. ESI responds
+// with 420 when the error limit is reached and 429 for other rate limits; both count.
+// The ESIError's RetryAfter says how long to wait before trying again.
+func IsRateLimited(err error) bool {
+	var esiErr *ESIError
+	return errors.As(err, &esiErr) && isRateLimitStatus(esiErr.StatusCode)
+}
+
+// isRateLimitStatus returns whether the status code is one ESI uses for rate limiting
+func isRateLimitStatus(code int) bool {
+	return code == 420 || code == http.StatusTooManyRequests
+}
+
+// retryAfter returns how long a rate limited response asks to wait, from its Retry-After
+// header (in seconds or as a date), or its error limit reset header if there's no Retry-After
+func retryAfter(h http.Header) time.Duration {
+	if value := h.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		// a date is relative to when the response was sent, so the local clock doesn't matter
+		if at, err := http.ParseTime(value); err == nil {
+			if sent, err := http.ParseTime(h.Get("Date")); err == nil && at.After(sent) {
+				return at.Sub(sent)
+			}
+			return 0
+		}
+	}
+	if seconds, err := strconv.Atoi(h.Get(ErrorLimitResetHeader)); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultRetryAfter
+}
+
+// retryRequest returns a copy of the request to send again, with a fresh body.
+// Returns false if the body can't be read again.
+func retryRequest(req *http.Request) (*http.Request, bool) {
+	retry := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return retry, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	retry.Body = body
+	return retry, true
+}
@@ -0,0 +1,87 @@
+package goesi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimitRetry(t *testing.T) {
+	for _, status := range []int{420, 429} {
+		clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)}
+		calls := 0
+		var bodies []string
+		e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+			calls++
+			if req.Body != nil {
+				body, _ := ioutil.ReadAll(req.Body)
+				bodies = append(bodies, string(body))
+			}
+			if calls == 1 {
+				return stubResponse(status, `{"error": "slow down"}`, "Retry-After", "3"), nil
+			}
+			return stubResponse(200, `{"ok": true}`), nil
+		})
+		e.SetClock(clock)
+		if _, err := e.Post("universe/ids", `["Jita"]`); err != nil {
+			t.Fatalf("Status %d: unexpected error: %s", status, err)
+		}
+		if calls != 2 || len(clock.waits) != 1 || clock.waits[0] != 3*time.Second {
+			t.Fatalf("Status %d: expected one retry after 3s, got %d calls and waits %v", status, calls, clock.waits)
+		}
+		if bodies[1] != `["Jita"]` {
+			t.Fatalf("Status %d: expected the body to be sent again, got %q", status, bodies)
+		}
+
+		e = newStubbedESI(func(req *http.Request) (*http.Response, error) {
+			return stubResponse(status, `{"error": "slow down"}`, "Retry-After", "7"), nil
+		})
+		e.MaxRetries = 0
+		_, err := e.Get("wars")
+		if !IsRateLimited(err) {
+			t.Fatalf("Status %d: expected a rate limited error, got %v", status, err)
+		}
+		if esiErr := err.(*ESIError); esiErr.RetryAfter != 7*time.Second {
+			t.Fatalf("Status %d: expected RetryAfter of 7s, got %s", status, esiErr.RetryAfter)
+		}
+	}
+	if IsRateLimited(&ESIError{StatusCode: 404}) {
+		t.Fatal("Expected a 404 not to be rate limited")
+	}
+}
+
+func TestRateLimitNoRetryForLongWaits(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2017, time.November, 9, 17, 0, 0, 0, time.UTC)}
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return stubResponse(420, `{"error": "error limited"}`, ErrorLimitResetHeader, "95"), nil
+	})
+	e.SetClock(clock)
+	_, err := e.Get("wars")
+	if !IsRateLimited(err) || err.(*ESIError).RetryAfter != 95*time.Second || calls != 1 {
+		t.Fatalf("Expected a single rate limited call waiting 95s, got %d calls and %v", calls, err)
+	}
+	if len(clock.waits) != 0 {
+		t.Fatalf("Unexpected waits %v", clock.waits)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	sent := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		header   http.Header
+		expected time.Duration
+	}{
+		{http.Header{"Retry-After": {"12"}}, 12 * time.Second},
+		{http.Header{"Retry-After": {sent.Add(30 * time.Second).Format(http.TimeFormat)}, "Date": {sent.Format(http.TimeFormat)}}, 30 * time.Second},
+		{http.Header{http.CanonicalHeaderKey(ErrorLimitResetHeader): {"40"}}, 40 * time.Second},
+		{http.Header{}, defaultRetryAfter},
+	}
+	for _, test := range tests {
+		if got := retryAfter(test.header); got != test.expected {
+			t.Fatalf("Headers %v: expected %s, got %s", test.header, test.expected, got)
+		}
+	}
+}
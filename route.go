@@ -0,0 +1,40 @@
+package goesi
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// routeOf returns the route of an ESI URL: the path without the version or surrounding slashes.
+// For example, "https://esi.tech.ccp.is/latest/universe/types/34/?page=1" has the route "universe/types/34".
+func routeOf(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	path := strings.Trim(parsed.Path, "/")
+	i := strings.Index(path, "/")
+	if i == -1 {
+		return ""
+	}
+	return path[i+1:]
+}
+
+// matchRoutePrefix returns the value in the map with the longest key that is a prefix of
+// the route, matching whole path segments, and whether there was one
+func matchRoutePrefix(m map[string]time.Duration, route string) (time.Duration, bool) {
+	var value time.Duration
+	longest := -1
+	for prefix, v := range m {
+		prefix = strings.Trim(prefix, "/")
+		if route != prefix && !strings.HasPrefix(route, prefix+"/") {
+			continue
+		}
+		if len(prefix) > longest {
+			longest = len(prefix)
+			value = v
+		}
+	}
+	return value, longest != -1
+}
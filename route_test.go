@@ -0,0 +1,32 @@
+package goesi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatchRoutePrefix(t *testing.T) {
+	overrides := map[string]time.Duration{
+		"universe":          time.Hour,
+		"universe/types/":   24 * time.Hour,
+		"characters/1/mail": time.Minute,
+	}
+	tests := []struct {
+		url      string
+		expected time.Duration
+		ok       bool
+	}{
+		{"https://esi.tech.ccp.is/latest/universe/types/34/", 24 * time.Hour, true},
+		{"https://esi.tech.ccp.is/latest/universe/systems/30000142/", time.Hour, true},
+		{"https://esi.tech.ccp.is/latest/universe/", time.Hour, true},
+		{"https://esi.tech.ccp.is/latest/universes/", 0, false},
+		{"https://esi.tech.ccp.is/latest/characters/1/mail/?last_mail_id=5", time.Minute, true},
+		{"https://esi.tech.ccp.is/latest/characters/12/mail/", 0, false},
+	}
+	for _, test := range tests {
+		ttl, ok := matchRoutePrefix(overrides, routeOf(test.url))
+		if ttl != test.expected || ok != test.ok {
+			t.Fatalf("URL '%s': expected %s %v, got %s %v", test.url, test.expected, test.ok, ttl, ok)
+		}
+	}
+}
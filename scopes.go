@@ -0,0 +1,270 @@
+package goesi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AllScopes are the scopes that can be requested from the SSO
+var AllScopes = []string{
+	"publicData",
+	"esi-alliances.read_contacts.v1",
+	"esi-assets.read_assets.v1",
+	"esi-assets.read_corporation_assets.v1",
+	"esi-bookmarks.read_character_bookmarks.v1",
+	"esi-bookmarks.read_corporation_bookmarks.v1",
+	"esi-calendar.read_calendar_events.v1",
+	"esi-calendar.respond_calendar_events.v1",
+	"esi-characters.read_agents_research.v1",
+	"esi-characters.read_blueprints.v1",
+	"esi-characters.read_chat_channels.v1",
+	"esi-characters.read_contacts.v1",
+	"esi-characters.read_corporation_roles.v1",
+	"esi-characters.read_fatigue.v1",
+	"esi-characters.read_fw_stats.v1",
+	"esi-characters.read_loyalty.v1",
+	"esi-characters.read_medals.v1",
+	"esi-characters.read_notifications.v1",
+	"esi-characters.read_opportunities.v1",
+	"esi-characters.read_standings.v1",
+	"esi-characters.read_titles.v1",
+	"esi-characters.write_contacts.v1",
+	"esi-characterstats.read.v1",
+	"esi-clones.read_clones.v1",
+	"esi-clones.read_implants.v1",
+	"esi-contracts.read_character_contracts.v1",
+	"esi-contracts.read_corporation_contracts.v1",
+	"esi-corporations.read_blueprints.v1",
+	"esi-corporations.read_contacts.v1",
+	"esi-corporations.read_container_logs.v1",
+	"esi-corporations.read_corporation_membership.v1",
+	"esi-corporations.read_divisions.v1",
+	"esi-corporations.read_facilities.v1",
+	"esi-corporations.read_fw_stats.v1",
+	"esi-corporations.read_medals.v1",
+	"esi-corporations.read_standings.v1",
+	"esi-corporations.read_starbases.v1",
+	"esi-corporations.read_structures.v1",
+	"esi-corporations.read_titles.v1",
+	"esi-corporations.track_members.v1",
+	"esi-fittings.read_fittings.v1",
+	"esi-fittings.write_fittings.v1",
+	"esi-fleets.read_fleet.v1",
+	"esi-fleets.write_fleet.v1",
+	"esi-industry.read_character_jobs.v1",
+	"esi-industry.read_character_mining.v1",
+	"esi-industry.read_corporation_jobs.v1",
+	"esi-industry.read_corporation_mining.v1",
+	"esi-killmails.read_corporation_killmails.v1",
+	"esi-killmails.read_killmails.v1",
+	"esi-location.read_location.v1",
+	"esi-location.read_online.v1",
+	"esi-location.read_ship_type.v1",
+	"esi-mail.organize_mail.v1",
+	"esi-mail.read_mail.v1",
+	"esi-mail.send_mail.v1",
+	"esi-markets.read_character_orders.v1",
+	"esi-markets.read_corporation_orders.v1",
+	"esi-markets.structure_markets.v1",
+	"esi-planets.manage_planets.v1",
+	"esi-planets.read_customs_offices.v1",
+	"esi-search.search_structures.v1",
+	"esi-skills.read_skillqueue.v1",
+	"esi-skills.read_skills.v1",
+	"esi-ui.open_window.v1",
+	"esi-ui.write_waypoint.v1",
+	"esi-universe.read_structures.v1",
+	"esi-wallet.read_character_wallet.v1",
+	"esi-wallet.read_corporation_wallets.v1",
+}
+
+// ValidateScope returns an error wrapping ErrUnknownScope if the scope is not in AllScopes
+func ValidateScope(scope string) error {
+	if !containsString(AllScopes, scope) {
+		return fmt.Errorf("%w: '%s'", ErrUnknownScope, scope)
+	}
+	return nil
+}
+
+// SetScopes validates the scopes and sets them as the scopes to request when authenticating
+func (e *ESI) SetScopes(scopes ...string) error {
+	for _, scope := range scopes {
+		if err := ValidateScope(scope); err != nil {
+			return err
+		}
+	}
+	e.Scope = strings.Join(scopes, " ")
+	return nil
+}
+
+// AddScope validates the scope and adds it to the scopes to request when authenticating
+func (e *ESI) AddScope(scope string) error {
+	if err := ValidateScope(scope); err != nil {
+		return err
+	}
+	current := strings.Fields(e.Scope)
+	if containsString(current, scope) {
+		return nil
+	}
+	e.Scope = strings.Join(append(current, scope), " ")
+	return nil
+}
+
+// RequestedScopes returns the scopes requested when authenticating, as set by SetScopes and AddScope
+func (e *ESI) RequestedScopes() []string {
+	return strings.Fields(e.Scope)
+}
+
+// GrantedScopes returns the scopes in the access token's claims, which are the ones the user
+// granted, to show alongside RequestedScopes, like on a settings page. It's nil if there's no
+// access token or its claims can't be read. The token isn't verified; use VerifyTokenSignature
+// to check the scopes before relying on them for access control.
+func (e *ESI) GrantedScopes() []string {
+	claims, ok := unverifiedClaims(e.AccessToken)
+	if !ok {
+		return nil
+	}
+	return claims.Scopes
+}
+
+// RouteScopes are the scopes that ESI routes need, keyed by the method and route as
+// they appear in ESI's swagger definition. Routes that don't need a scope are not listed.
+var RouteScopes = map[string]string{
+	"GET /alliances/{alliance_id}/contacts/":                        "esi-alliances.read_contacts.v1",
+	"GET /alliances/{alliance_id}/contacts/labels/":                 "esi-alliances.read_contacts.v1",
+	"GET /characters/{character_id}/agents_research/":               "esi-characters.read_agents_research.v1",
+	"GET /characters/{character_id}/assets/":                        "esi-assets.read_assets.v1",
+	"POST /characters/{character_id}/assets/locations/":             "esi-assets.read_assets.v1",
+	"POST /characters/{character_id}/assets/names/":                 "esi-assets.read_assets.v1",
+	"GET /characters/{character_id}/blueprints/":                    "esi-characters.read_blueprints.v1",
+	"GET /characters/{character_id}/bookmarks/":                     "esi-bookmarks.read_character_bookmarks.v1",
+	"GET /characters/{character_id}/bookmarks/folders/":             "esi-bookmarks.read_character_bookmarks.v1",
+	"GET /characters/{character_id}/calendar/":                      "esi-calendar.read_calendar_events.v1",
+	"GET /characters/{character_id}/calendar/{event_id}/":           "esi-calendar.read_calendar_events.v1",
+	"PUT /characters/{character_id}/calendar/{event_id}/":           "esi-calendar.respond_calendar_events.v1",
+	"GET /characters/{character_id}/calendar/{event_id}/attendees/": "esi-calendar.read_calendar_events.v1",
+	"GET /characters/{character_id}/clones/":                        "esi-clones.read_clones.v1",
+	"GET /characters/{character_id}/contacts/":                      "esi-characters.read_contacts.v1",
+	"POST /characters/{character_id}/contacts/":                     "esi-characters.write_contacts.v1",
+	"PUT /characters/{character_id}/contacts/":                      "esi-characters.write_contacts.v1",
+	"DELETE /characters/{character_id}/contacts/":                   "esi-characters.write_contacts.v1",
+	"GET /characters/{character_id}/contacts/labels/":               "esi-characters.read_contacts.v1",
+	"GET /characters/{character_id}/contracts/":                     "esi-contracts.read_character_contracts.v1",
+	"GET /characters/{character_id}/contracts/{contract_id}/bids/":  "esi-contracts.read_character_contracts.v1",
+	"GET /characters/{character_id}/contracts/{contract_id}/items/": "esi-contracts.read_character_contracts.v1",
+	"GET /characters/{character_id}/fatigue/":                       "esi-characters.read_fatigue.v1",
+	"GET /characters/{character_id}/fittings/":                      "esi-fittings.read_fittings.v1",
+	"POST /characters/{character_id}/fittings/":                     "esi-fittings.write_fittings.v1",
+	"DELETE /characters/{character_id}/fittings/{fitting_id}/":      "esi-fittings.write_fittings.v1",
+	"GET /characters/{character_id}/fleet/":                         "esi-fleets.read_fleet.v1",
+	"GET /characters/{character_id}/fw/stats/":                      "esi-characters.read_fw_stats.v1",
+	"GET /characters/{character_id}/implants/":                      "esi-clones.read_implants.v1",
+	"GET /characters/{character_id}/industry/jobs/":                 "esi-industry.read_character_jobs.v1",
+	"GET /characters/{character_id}/killmails/recent/":              "esi-killmails.read_killmails.v1",
+	"GET /characters/{character_id}/location/":                      "esi-location.read_location.v1",
+	"GET /characters/{character_id}/loyalty/points/":                "esi-characters.read_loyalty.v1",
+	"GET /characters/{character_id}/mail/":                          "esi-mail.read_mail.v1",
+	"POST /characters/{character_id}/mail/":                         "esi-mail.send_mail.v1",
+	"GET /characters/{character_id}/mail/labels/":                   "esi-mail.read_mail.v1",
+	"POST /characters/{character_id}/mail/labels/":                  "esi-mail.organize_mail.v1",
+	"DELETE /characters/{character_id}/mail/labels/{label_id}/":     "esi-mail.organize_mail.v1",
+	"GET /characters/{character_id}/mail/lists/":                    "esi-mail.read_mail.v1",
+	"GET /characters/{character_id}/mail/{mail_id}/":                "esi-mail.read_mail.v1",
+	"PUT /characters/{character_id}/mail/{mail_id}/":                "esi-mail.organize_mail.v1",
+	"DELETE /characters/{character_id}/mail/{mail_id}/":             "esi-mail.organize_mail.v1",
+	"GET /characters/{character_id}/medals/":                        "esi-characters.read_medals.v1",
+	"GET /characters/{character_id}/mining/":                        "esi-industry.read_character_mining.v1",
+	"GET /characters/{character_id}/notifications/":                 "esi-characters.read_notifications.v1",
+	"GET /characters/{character_id}/notifications/contacts/":        "esi-characters.read_notifications.v1",
+	"GET /characters/{character_id}/online/":                        "esi-location.read_online.v1",
+	"GET /characters/{character_id}/opportunities/":                 "esi-characters.read_opportunities.v1",
+	"GET /characters/{character_id}/orders/":                        "esi-markets.read_character_orders.v1",
+	"GET /characters/{character_id}/orders/history/":                "esi-markets.read_character_orders.v1",
+	"GET /characters/{character_id}/planets/":                       "esi-planets.manage_planets.v1",
+	"GET /characters/{character_id}/planets/{planet_id}/":           "esi-planets.manage_planets.v1",
+	"GET /characters/{character_id}/roles/":                         "esi-characters.read_corporation_roles.v1",
+	"GET /characters/{character_id}/search/":                        "esi-search.search_structures.v1",
+	"GET /characters/{character_id}/ship/":                          "esi-location.read_ship_type.v1",
+	"GET /characters/{character_id}/skillqueue/":                    "esi-skills.read_skillqueue.v1",
+	"GET /characters/{character_id}/skills/":                        "esi-skills.read_skills.v1",
+	"GET /characters/{character_id}/standings/":                     "esi-characters.read_standings.v1",
+	"GET /characters/{character_id}/titles/":                        "esi-characters.read_titles.v1",
+	"GET /characters/{character_id}/wallet/":                        "esi-wallet.read_character_wallet.v1",
+	"GET /characters/{character_id}/wallet/journal/":                "esi-wallet.read_character_wallet.v1",
+	"GET /characters/{character_id}/wallet/transactions/":           "esi-wallet.read_character_wallet.v1",
+	"GET /corporation/{corporation_id}/mining/extractions/":         "esi-industry.read_corporation_mining.v1",
+	"GET /corporation/{corporation_id}/mining/observers/":           "esi-industry.read_corporation_mining.v1",
+	"GET /corporations/{corporation_id}/assets/":                    "esi-assets.read_corporation_assets.v1",
+	"GET /corporations/{corporation_id}/blueprints/":                "esi-corporations.read_blueprints.v1",
+	"GET /corporations/{corporation_id}/bookmarks/":                 "esi-bookmarks.read_corporation_bookmarks.v1",
+	"GET /corporations/{corporation_id}/contacts/":                  "esi-corporations.read_contacts.v1",
+	"GET /corporations/{corporation_id}/containers/logs/":           "esi-corporations.read_container_logs.v1",
+	"GET /corporations/{corporation_id}/contracts/":                 "esi-contracts.read_corporation_contracts.v1",
+	"GET /corporations/{corporation_id}/customs_offices/":           "esi-planets.read_customs_offices.v1",
+	"GET /corporations/{corporation_id}/divisions/":                 "esi-corporations.read_divisions.v1",
+	"GET /corporations/{corporation_id}/facilities/":                "esi-corporations.read_facilities.v1",
+	"GET /corporations/{corporation_id}/fw/stats/":                  "esi-corporations.read_fw_stats.v1",
+	"GET /corporations/{corporation_id}/industry/jobs/":             "esi-industry.read_corporation_jobs.v1",
+	"GET /corporations/{corporation_id}/killmails/recent/":          "esi-killmails.read_corporation_killmails.v1",
+	"GET /corporations/{corporation_id}/medals/":                    "esi-corporations.read_medals.v1",
+	"GET /corporations/{corporation_id}/members/":                   "esi-corporations.read_corporation_membership.v1",
+	"GET /corporations/{corporation_id}/membertracking/":            "esi-corporations.track_members.v1",
+	"GET /corporations/{corporation_id}/orders/":                    "esi-markets.read_corporation_orders.v1",
+	"GET /corporations/{corporation_id}/standings/":                 "esi-corporations.read_standings.v1",
+	"GET /corporations/{corporation_id}/starbases/":                 "esi-corporations.read_starbases.v1",
+	"GET /corporations/{corporation_id}/structures/":                "esi-corporations.read_structures.v1",
+	"GET /corporations/{corporation_id}/titles/":                    "esi-corporations.read_titles.v1",
+	"GET /corporations/{corporation_id}/wallets/":                   "esi-wallet.read_corporation_wallets.v1",
+	"GET /fleets/{fleet_id}/":                                       "esi-fleets.read_fleet.v1",
+	"PUT /fleets/{fleet_id}/":                                       "esi-fleets.write_fleet.v1",
+	"GET /fleets/{fleet_id}/members/":                               "esi-fleets.read_fleet.v1",
+	"POST /fleets/{fleet_id}/members/":                              "esi-fleets.write_fleet.v1",
+	"PUT /fleets/{fleet_id}/members/{member_id}/":                   "esi-fleets.write_fleet.v1",
+	"DELETE /fleets/{fleet_id}/members/{member_id}/":                "esi-fleets.write_fleet.v1",
+	"GET /fleets/{fleet_id}/wings/":                                 "esi-fleets.read_fleet.v1",
+	"POST /fleets/{fleet_id}/wings/":                                "esi-fleets.write_fleet.v1",
+	"GET /markets/structures/{structure_id}/":                       "esi-markets.structure_markets.v1",
+	"POST /ui/autopilot/waypoint/":                                  "esi-ui.write_waypoint.v1",
+	"POST /ui/openwindow/contract/":                                 "esi-ui.open_window.v1",
+	"POST /ui/openwindow/information/":                              "esi-ui.open_window.v1",
+	"POST /ui/openwindow/marketdetails/":                            "esi-ui.open_window.v1",
+	"POST /ui/openwindow/newmail/":                                  "esi-ui.open_window.v1",
+	"GET /universe/structures/{structure_id}/":                      "esi-universe.read_structures.v1",
+}
+
+// RequiredScope returns the scope that a call to the path with the method needs, and
+// whether it needs one. The path can be in the same form as passed to Get, like
+// "characters/90000001/wallet", or as in ESI's swagger definition. Use this to request
+// only the scopes for the features a user enables, instead of every scope.
+// If more than one route matches, like "mail/labels" and "mail/{mail_id}", the
+// route with the fewest placeholders is used.
+func RequiredScope(method, path string) (string, bool) {
+	method = strings.ToUpper(method)
+	segments := splitPath(path)
+	best, bestPlaceholders := "", -1
+	for key, scope := range RouteScopes {
+		parts := strings.SplitN(key, " ", 2)
+		if parts[0] != method {
+			continue
+		}
+		route := splitPath(parts[1])
+		if !matchRoute(route, segments) {
+			continue
+		}
+		if n := placeholders(route); bestPlaceholders == -1 || n < bestPlaceholders {
+			best, bestPlaceholders = scope, n
+		}
+	}
+	return best, bestPlaceholders != -1
+}
+
+// placeholders returns how many of the route's segments are placeholders like "{character_id}"
+func placeholders(route []string) int {
+	n := 0
+	for _, part := range route {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,92 @@
+package goesi
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		method   string
+		path     string
+		expected string
+		ok       bool
+	}{
+		{"GET", "/characters/{character_id}/wallet/", "esi-wallet.read_character_wallet.v1", true},
+		{"GET", "characters/90000001/wallet", "esi-wallet.read_character_wallet.v1", true},
+		{"get", "characters/90000001/wallet/journal/?page=2", "esi-wallet.read_character_wallet.v1", true},
+		{"GET", "characters/90000001/mail/labels", "esi-mail.read_mail.v1", true},
+		{"POST", "characters/90000001/mail/labels", "esi-mail.organize_mail.v1", true},
+		{"PUT", "characters/90000001/calendar/5", "esi-calendar.respond_calendar_events.v1", true},
+		{"DELETE", "characters/90000001/wallet", "", false},
+		{"GET", "universe/types/34", "", false},
+	}
+	for _, test := range tests {
+		scope, ok := RequiredScope(test.method, test.path)
+		if scope != test.expected || ok != test.ok {
+			t.Fatalf("%s '%s': expected '%s' %v, got '%s' %v", test.method, test.path, test.expected, test.ok, scope, ok)
+		}
+	}
+}
+
+func TestRouteScopesAreKnown(t *testing.T) {
+	for route, scope := range RouteScopes {
+		if err := ValidateScope(scope); err != nil {
+			t.Fatalf("Route '%s': %s", route, err)
+		}
+		if parts := strings.SplitN(route, " ", 2); len(parts) != 2 {
+			t.Fatalf("Route '%s' has no method", route)
+		}
+	}
+}
+
+func TestGrantedScopes(t *testing.T) {
+	e := New("clientID", "clientSecret", "http://localhost/callback")
+	if err := e.SetScopes("esi-skills.read_skills.v1", "esi-wallet.read_character_wallet.v1"); err != nil {
+		t.Fatal(err)
+	}
+	if scopes := e.RequestedScopes(); !reflect.DeepEqual(scopes, []string{"esi-skills.read_skills.v1", "esi-wallet.read_character_wallet.v1"}) {
+		t.Fatalf("Unexpected requested scopes: %v", scopes)
+	}
+	if scopes := e.GrantedScopes(); scopes != nil {
+		t.Fatalf("Expected no granted scopes without a token, got %v", scopes)
+	}
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub": "CHARACTER:EVE:90000001", "scp": "esi-skills.read_skills.v1"}`))
+	e.AccessToken = "e30." + payload + ".c2ln"
+	if scopes := e.GrantedScopes(); !reflect.DeepEqual(scopes, []string{"esi-skills.read_skills.v1"}) {
+		t.Fatalf("Unexpected granted scopes: %v", scopes)
+	}
+}
+
+func TestSetScopes(t *testing.T) {
+	e := New("clientID", "clientSecret", "http://localhost/callback")
+	if err := ValidateScope("esi-skills.read_skills.v1"); err != nil {
+		t.Fatalf("Expected a known scope to be valid, got %v", err)
+	}
+	if err := e.SetScopes("esi-skills.read_skills.v1", "esi-wallet.read_character_wallet.v1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetScopes("esi-skills.read_skills.v1", "esi-made-up.v1"); !errors.Is(err, ErrUnknownScope) {
+		t.Fatalf("Expected ErrUnknownScope, got %v", err)
+	}
+	if err := e.AddScope("esi-made-up.v1"); !errors.Is(err, ErrUnknownScope) {
+		t.Fatalf("Expected ErrUnknownScope, got %v", err)
+	}
+	if e.Scope != "esi-skills.read_skills.v1 esi-wallet.read_character_wallet.v1" {
+		t.Fatalf("Expected unknown scopes to leave the scopes unchanged, got %q", e.Scope)
+	}
+	for i := 0; i < 2; i++ {
+		if err := e.AddScope("esi-mail.read_mail.v1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.AddScope("esi-skills.read_skills.v1"); err != nil {
+		t.Fatal(err)
+	}
+	if e.Scope != "esi-skills.read_skills.v1 esi-wallet.read_character_wallet.v1 esi-mail.read_mail.v1" {
+		t.Fatalf("Expected each scope to be added once, got %q", e.Scope)
+	}
+}
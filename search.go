@@ -0,0 +1,72 @@
+package goesi
+
+import (
+	"github.com/Jeffail/gabs"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Categories that can be searched
+const (
+	SearchAgent         = "agent"
+	SearchAlliance      = "alliance"
+	SearchCharacter     = "character"
+	SearchConstellation = "constellation"
+	SearchCorporation   = "corporation"
+	SearchFaction       = "faction"
+	SearchInventoryType = "inventory_type"
+	SearchRegion        = "region"
+	SearchSolarSystem   = "solar_system"
+	SearchStation       = "station"
+	SearchStructure     = "structure"
+)
+
+// SearchResults are the IDs found by a search, per category
+type SearchResults struct {
+	Agent         []int32 `json:"agent"`
+	Alliance      []int32 `json:"alliance"`
+	Character     []int32 `json:"character"`
+	Constellation []int32 `json:"constellation"`
+	Corporation   []int32 `json:"corporation"`
+	Faction       []int32 `json:"faction"`
+	InventoryType []int32 `json:"inventory_type"`
+	Region        []int32 `json:"region"`
+	SolarSystem   []int32 `json:"solar_system"`
+	Station       []int32 `json:"station"`
+	Structure     []int64 `json:"structure"`
+}
+
+// Search searches for the query in the categories (see the Search* constants).
+// If strict is set, only exact matches are returned.
+// With an access token the search is made as the token's character, which is
+// required for the structure category and needs the esi-search.search_structures.v1
+// scope; without one, the public search is used.
+func (e *ESI) Search(categories []string, query string, strict bool) (*SearchResults, error) {
+	params := url.Values{
+		"categories": []string{strings.Join(categories, ",")},
+		"search":     []string{query},
+		"strict":     []string{strconv.FormatBool(strict)},
+	}
+	var data *gabs.Container
+	var err error
+	if e.AccessToken != "" {
+		var id int32
+		id, err = e.characterID()
+		if err != nil {
+			return nil, err
+		}
+		data, err = e.GetWithParams("characters/%d/search", params, id)
+	} else {
+		data, err = e.GetWithParams("search", params)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var results SearchResults
+	if err := decode(data, &results); err != nil {
+		e.log.Error("Error parsing search response", "query", query, "error", err)
+		return nil, err
+	}
+	return &results, nil
+}
@@ -0,0 +1,42 @@
+package goesi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		path  string
+	}{
+		{"public", "", "/search/"},
+		{"as the character", "token", "/characters/90000001/search/"},
+	}
+	for _, test := range tests {
+		e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasSuffix(req.URL.Path, test.path) {
+				t.Fatalf("%s: unexpected request to %s", test.name, req.URL)
+			}
+			query := req.URL.Query()
+			if query.Get("categories") != "character,corporation" || query.Get("search") != "Goesi & Co" || query.Get("strict") != "true" {
+				t.Fatalf("%s: unexpected params %v", test.name, query)
+			}
+			if !strings.Contains(req.URL.RawQuery, "search=Goesi+%26+Co") {
+				t.Fatalf("%s: expected the query to be encoded, got '%s'", test.name, req.URL.RawQuery)
+			}
+			return stubResponse(200, `{"character": [90000001], "corporation": [98000001, 98000002]}`), nil
+		})
+		e.AccessToken = test.token
+		e.CharacterID = 90000001
+		results, err := e.Search([]string{SearchCharacter, SearchCorporation}, "Goesi & Co", true)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", test.name, err)
+		}
+		if len(results.Character) != 1 || results.Character[0] != 90000001 || len(results.Corporation) != 2 || len(results.Alliance) != 0 {
+			t.Fatalf("%s: unexpected results %+v", test.name, results)
+		}
+	}
+}
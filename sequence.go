@@ -0,0 +1,41 @@
+package goesi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Operation is a call for Sequence to make: its method, GET, POST, PUT, or DELETE, the path,
+// in the same form as passed to Get, and for POST and PUT, the body
+type Operation struct {
+	Method string
+	Path   string
+	Body   string
+}
+
+// Sequence makes the calls in order, like setting a route's waypoints and then opening a
+// window, stopping at the first that fails. The failure is returned as a *SequenceError saying
+// which step it was. ESI has no transactions, so the steps before it aren't undone; the error
+// says how many of them were made, so the caller can undo them or carry on from the failed step.
+func (e *ESI) Sequence(ops []Operation) error {
+	for i, op := range ops {
+		var err error
+		switch strings.ToUpper(op.Method) {
+		case "GET":
+			_, err = e.Get(op.Path)
+		case "POST":
+			_, err = e.Post(op.Path, op.Body)
+		case "PUT":
+			_, err = e.Put(op.Path, op.Body)
+		case "DELETE":
+			_, err = e.Delete(op.Path)
+		default:
+			err = fmt.Errorf("Unsupported method '%s'", op.Method)
+		}
+		if err != nil {
+			e.log.Error("Sequence step failed", "step", i, "method", op.Method, "path", op.Path, "error", err)
+			return &SequenceError{Step: i, Operation: op, Err: err}
+		}
+	}
+	return nil
+}
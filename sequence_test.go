@@ -0,0 +1,42 @@
+package goesi
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSequence(t *testing.T) {
+	var requests []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		if strings.Contains(req.URL.Path, "openwindow") {
+			return stubResponse(403, `{"error": "token is not valid for scope(s): esi-ui.open_window.v1"}`), nil
+		}
+		return stubResponse(204, ""), nil
+	})
+	err := e.Sequence([]Operation{
+		{Method: "POST", Path: "ui/autopilot/waypoint?destination_id=30000142"},
+		{Method: "POST", Path: "ui/openwindow/information?target_id=30000142"},
+		{Method: "DELETE", Path: "characters/90000001/fittings/1"},
+	})
+	var seqErr *SequenceError
+	if !errors.As(err, &seqErr) || seqErr.Step != 1 || seqErr.Operation.Path != "ui/openwindow/information?target_id=30000142" {
+		t.Fatalf("Expected a *SequenceError for step 1, got %v", err)
+	}
+	var esiErr *ESIError
+	if !errors.As(err, &esiErr) || esiErr.StatusCode != 403 {
+		t.Fatalf("Expected the step's *ESIError to be wrapped, got %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("Expected the sequence to stop at the failed step, got %q", requests)
+	}
+
+	if err := e.Sequence([]Operation{{Method: "PATCH", Path: "characters/90000001"}}); !errors.As(err, &seqErr) || seqErr.Step != 0 {
+		t.Fatalf("Expected an unsupported method to fail its step, got %v", err)
+	}
+	if err := e.Sequence([]Operation{{Method: "post", Path: "ui/autopilot/waypoint?destination_id=30000142"}}); err != nil {
+		t.Fatal(err)
+	}
+}
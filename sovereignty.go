@@ -0,0 +1,55 @@
+package goesi
+
+// SovereigntySystem is who holds sovereignty over a solar system. Only one of AllianceID,
+// with CorporationID, or FactionID is set; none are for unclaimed systems.
+type SovereigntySystem struct {
+	SystemID      int32 `json:"system_id"`
+	AllianceID    int32 `json:"alliance_id"`
+	CorporationID int32 `json:"corporation_id"`
+	FactionID     int32 `json:"faction_id"`
+}
+
+// FWTally is a count over the last day, the last week, and overall
+type FWTally struct {
+	Yesterday int32 `json:"yesterday"`
+	LastWeek  int32 `json:"last_week"`
+	Total     int32 `json:"total"`
+}
+
+// FWFactionStats is how a faction is doing in faction warfare
+type FWFactionStats struct {
+	FactionID         int32   `json:"faction_id"`
+	Pilots            int32   `json:"pilots"`
+	SystemsControlled int32   `json:"systems_controlled"`
+	Kills             FWTally `json:"kills"`
+	VictoryPoints     FWTally `json:"victory_points"`
+}
+
+// SovereigntyMap fetches who holds sovereignty over every solar system. The response is
+// large and changes once an hour, so it's best to let the cache serve repeated calls.
+func (e *ESI) SovereigntyMap() ([]SovereigntySystem, error) {
+	data, err := e.GetPublic("sovereignty/map")
+	if err != nil {
+		return nil, err
+	}
+	var systems []SovereigntySystem
+	if err := decode(data, &systems); err != nil {
+		e.log.Error("Error parsing sovereignty map response", "error", err)
+		return nil, err
+	}
+	return systems, nil
+}
+
+// FactionWarfareStats fetches the faction warfare stats of each faction that takes part in it
+func (e *ESI) FactionWarfareStats() ([]FWFactionStats, error) {
+	data, err := e.GetPublic("fw/stats")
+	if err != nil {
+		return nil, err
+	}
+	var stats []FWFactionStats
+	if err := decode(data, &stats); err != nil {
+		e.log.Error("Error parsing faction warfare stats response", "error", err)
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,40 @@
+package goesi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSovereigntyMap(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(req.URL.Path, "/sovereignty/map/") || req.Header.Get("Authorization") != "" {
+			t.Fatalf("Unexpected request to %s", req.URL)
+		}
+		return stubResponse(200, `[{"system_id": 30000001, "faction_id": 500007}, {"system_id": 30004708, "alliance_id": 99000001, "corporation_id": 98000001}, {"system_id": 31000005}]`), nil
+	})
+	e.AccessToken = "token"
+	systems, err := e.SovereigntyMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(systems) != 3 || systems[0].FactionID != 500007 || systems[1].AllianceID != 99000001 || systems[2].AllianceID != 0 {
+		t.Fatalf("Unexpected systems: %+v", systems)
+	}
+}
+
+func TestFactionWarfareStats(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(req.URL.Path, "/fw/stats/") {
+			t.Fatalf("Unexpected request to %s", req.URL)
+		}
+		return stubResponse(200, `[{"faction_id": 500001, "pilots": 28863, "systems_controlled": 20, "kills": {"yesterday": 136, "last_week": 1244, "total": 464049}, "victory_points": {"yesterday": 202740, "last_week": 1197520, "total": 100678025}}]`), nil
+	})
+	stats, err := e.FactionWarfareStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].SystemsControlled != 20 || stats[0].Kills.LastWeek != 1244 || stats[0].VictoryPoints.Total != 100678025 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,129 @@
+package goesi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// swaggerMaxAge is how long a fetched swagger definition is used before fetching it again
+const swaggerMaxAge = 24 * time.Hour
+
+// swaggerCache holds the routes from ESI's swagger definition, per ESI version.
+// The definition is large and rarely changes, so it's kept separately from the
+// response cache and is not dropped by ClearCache.
+type swaggerCache struct {
+	mu       sync.Mutex
+	versions map[string]swaggerRoutes
+}
+
+// swaggerRoutes are the routes of one version of ESI, each split into its path segments
+type swaggerRoutes struct {
+	routes  [][]string
+	fetched time.Time
+}
+
+// PathExists returns whether the path is a route in the ESI version the struct is using,
+// according to ESI's swagger definition. The path is in the same form as passed to Get,
+// like "characters/90000001/wallet". Use this to catch typos and removed routes before
+// making a call that would otherwise return a 404.
+func (e *ESI) PathExists(path string) (bool, error) {
+	if e.swagger == nil {
+		e.swagger = &swaggerCache{}
+	}
+	routes, err := e.swagger.routes(e)
+	if err != nil {
+		return false, err
+	}
+	segments := splitPath(path)
+	for _, route := range routes {
+		if matchRoute(route, segments) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// routes returns the routes for the ESI version, fetching the swagger definition if needed
+func (s *swaggerCache) routes(e *ESI) ([][]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cached, ok := s.versions[e.Version]
+	if ok && e.now().Sub(cached.fetched) < swaggerMaxAge {
+		return cached.routes, nil
+	}
+	routes, err := fetchSwaggerRoutes(e)
+	if err != nil {
+		return nil, err
+	}
+	if s.versions == nil {
+		s.versions = make(map[string]swaggerRoutes)
+	}
+	s.versions[e.Version] = swaggerRoutes{routes, e.now()}
+	return routes, nil
+}
+
+// fetchSwaggerRoutes downloads the swagger definition for the ESI version and returns its routes
+func fetchSwaggerRoutes(e *ESI) ([][]string, error) {
+	url := BaseURL + e.Version + "/swagger.json"
+	e.log.Info("Fetching swagger definition", "url", url)
+	req, err := e.newRequest("GET", url, nil)
+	if err != nil {
+		e.log.Error("Error creating a new request struct", "error", err)
+		return nil, err
+	}
+	setupPublicHeaders(e, req)
+	resp, err := e.do(req)
+	if err != nil {
+		e.log.Error("Error making request to ESI", "url", url, "error", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected status code %d fetching swagger definition", resp.StatusCode)
+	}
+	var spec struct {
+		Paths map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&spec); err != nil {
+		e.log.Error("Error parsing swagger definition", "url", url, "error", err)
+		return nil, err
+	}
+	routes := make([][]string, 0, len(spec.Paths))
+	for route := range spec.Paths {
+		routes = append(routes, splitPath(route))
+	}
+	return routes, nil
+}
+
+// splitPath splits a path into its segments, ignoring any query string and surrounding slashes
+func splitPath(path string) []string {
+	if i := strings.Index(path, "?"); i != -1 {
+		path = path[:i]
+	}
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// matchRoute returns whether the path segments match the route's segments,
+// where route segments like "{character_id}" match any value
+func matchRoute(route, segments []string) bool {
+	if len(route) != len(segments) {
+		return false
+	}
+	for i, part := range route {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			continue
+		}
+		if part != segments[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package goesi
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPathExists(t *testing.T) {
+	calls := 0
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if req.URL.Path != "/latest/swagger.json" {
+			t.Fatalf("Unexpected request to %s", req.URL)
+		}
+		return stubResponse(200, `{"paths": {"/status/": {}, "/characters/{character_id}/wallet/": {}}}`), nil
+	})
+	tests := []struct {
+		path   string
+		exists bool
+	}{
+		{"status", true},
+		{"characters/90000001/wallet", true},
+		{"/characters/90000001/wallet/", true},
+		{"characters/90000001/walet", false},
+		{"characters/90000001", false},
+	}
+	for _, test := range tests {
+		exists, err := e.PathExists(test.path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if exists != test.exists {
+			t.Fatalf("Path '%s': expected %v, got %v", test.path, test.exists, exists)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("Expected the swagger definition to be fetched once, fetched %d times", calls)
+	}
+}
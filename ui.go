@@ -0,0 +1,13 @@
+package goesi
+
+import (
+	"fmt"
+)
+
+// OpenInformationWindow opens the information window in the game client for the target,
+// like a character, corporation, alliance, or solar system. Needs the esi-ui.open_window.v1
+// scope. The call is never cached, even with CachePOST set, as it's made for what it does.
+func (e *ESI) OpenInformationWindow(targetID int32) error {
+	_, _, err := e.send("POST", fmt.Sprintf("ui/openwindow/information?target_id=%d", targetID), "", false)
+	return err
+}
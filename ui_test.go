@@ -0,0 +1,27 @@
+package goesi
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestOpenInformationWindow(t *testing.T) {
+	var requests []string
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil && req.ContentLength != 0 {
+			t.Fatalf("Expected no body, got %d bytes", req.ContentLength)
+		}
+		requests = append(requests, req.Method+" "+req.URL.Path+"?"+req.URL.RawQuery)
+		return stubResponse(204, ""), nil
+	})
+	e.AccessToken = "token"
+	e.CachePOST = true
+	for i := 0; i < 2; i++ {
+		if err := e.OpenInformationWindow(90000001); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(requests) != 2 || requests[0] != "POST /latest/ui/openwindow/information/?target_id=90000001" {
+		t.Fatalf("Expected a POST with the target for each call, got %q", requests)
+	}
+}
@@ -0,0 +1,335 @@
+package goesi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Jeffail/gabs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// TypeInfo is the information about an item type
+type TypeInfo struct {
+	TypeID         int32   `json:"type_id"`
+	Name           string  `json:"name"`
+	Description    string  `json:"description"`
+	GroupID        int32   `json:"group_id"`
+	MarketGroupID  int32   `json:"market_group_id"`
+	Published      bool    `json:"published"`
+	Mass           float64 `json:"mass"`
+	Volume         float64 `json:"volume"`
+	PackagedVolume float64 `json:"packaged_volume"`
+	Capacity       float64 `json:"capacity"`
+	Radius         float64 `json:"radius"`
+	PortionSize    int32   `json:"portion_size"`
+	IconID         int32   `json:"icon_id"`
+	GraphicID      int32   `json:"graphic_id"`
+}
+
+// Types fetches the information about each of the item types, keyed by type ID.
+// Type data rarely changes, so it's cached for a day by default (see CacheTTLOverrides).
+func (e *ESI) Types(ids []int32) (map[int32]*TypeInfo, error) {
+	paths := make([]string, len(ids))
+	for i, id := range ids {
+		paths[i] = fmt.Sprintf("universe/types/%d", id)
+	}
+	responses, err := e.getAll(paths)
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[int32]*TypeInfo, len(ids))
+	for i, response := range responses {
+		var info TypeInfo
+		if err := decode(response, &info); err != nil {
+			e.log.Error("Error parsing type response", "typeID", ids[i], "error", err)
+			return nil, err
+		}
+		types[ids[i]] = &info
+	}
+	return types, nil
+}
+
+// detailRoutes are the routes to fetch the details of each category returned by universe/ids
+var detailRoutes = map[string]string{
+	"agents":          "characters/%d",
+	"alliances":       "alliances/%d",
+	"characters":      "characters/%d",
+	"constellations":  "universe/constellations/%d",
+	"corporations":    "corporations/%d",
+	"inventory_types": "universe/types/%d",
+	"regions":         "universe/regions/%d",
+	"stations":        "universe/stations/%d",
+	"systems":         "universe/systems/%d",
+}
+
+// ResolveAndFetch resolves the name to an ID in the category with universe/ids, then fetches
+// the details for that ID. The category is one of the keys in a universe/ids response, like
+// "characters", "corporations", "inventory_types", or "systems". If there's no match,
+// ErrNameNotFound is returned; if there are several matches and none of them is exactly
+// the name, ErrAmbiguousName is returned.
+func (e *ESI) ResolveAndFetch(name, category string) (*gabs.Container, error) {
+	route, ok := detailRoutes[category]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnsupportedCategory, category)
+	}
+	body, err := json.Marshal([]string{name})
+	if err != nil {
+		return nil, err
+	}
+	data, err := e.Post("universe/ids", string(body))
+	if err != nil {
+		return nil, err
+	}
+	var resolved map[string][]struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := decode(data, &resolved); err != nil {
+		e.log.Error("Error parsing universe/ids response", "name", name, "error", err)
+		return nil, err
+	}
+	matches := resolved[category]
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: no %s named '%s'", ErrNameNotFound, category, name)
+	case 1:
+		return e.Get(route, matches[0].ID)
+	}
+	for _, match := range matches {
+		if strings.EqualFold(match.Name, name) {
+			return e.Get(route, match.ID)
+		}
+	}
+	return nil, fmt.Errorf("%w: %d %s match '%s'", ErrAmbiguousName, len(matches), category, name)
+}
+
+// Position is a point in space, in meters
+type Position struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+// RegionInfo is the information about a region
+type RegionInfo struct {
+	RegionID       int32   `json:"region_id"`
+	Name           string  `json:"name"`
+	Description    string  `json:"description"`
+	Constellations []int32 `json:"constellations"`
+}
+
+// ConstellationInfo is the information about a constellation
+type ConstellationInfo struct {
+	ConstellationID int32    `json:"constellation_id"`
+	Name            string   `json:"name"`
+	RegionID        int32    `json:"region_id"`
+	Systems         []int32  `json:"systems"`
+	Position        Position `json:"position"`
+}
+
+// SystemInfo is the information about a solar system
+type SystemInfo struct {
+	SystemID        int32    `json:"system_id"`
+	Name            string   `json:"name"`
+	ConstellationID int32    `json:"constellation_id"`
+	SecurityStatus  float64  `json:"security_status"`
+	SecurityClass   string   `json:"security_class"`
+	StarID          int32    `json:"star_id"`
+	Stargates       []int32  `json:"stargates"`
+	Stations        []int32  `json:"stations"`
+	Position        Position `json:"position"`
+}
+
+// Faction is one of the NPC factions, like the Caldari State
+type Faction struct {
+	FactionID            int32   `json:"faction_id"`
+	Name                 string  `json:"name"`
+	Description          string  `json:"description"`
+	CorporationID        int32   `json:"corporation_id"`
+	MilitiaCorporationID int32   `json:"militia_corporation_id"`
+	SolarSystemID        int32   `json:"solar_system_id"`
+	SizeFactor           float64 `json:"size_factor"`
+	StationCount         int32   `json:"station_count"`
+	StationSystemCount   int32   `json:"station_system_count"`
+	IsUnique             bool    `json:"is_unique"`
+}
+
+// Race is one of the races characters can be, like the Caldari
+type Race struct {
+	RaceID      int32  `json:"race_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// AllianceID is the ID of the race's faction
+	AllianceID int32 `json:"alliance_id"`
+}
+
+// Factions fetches the information about every NPC faction. Factions rarely change, so
+// they're cached for a day by default (see CacheTTLOverrides).
+func (e *ESI) Factions() ([]Faction, error) {
+	data, err := e.GetPublic("universe/factions")
+	if err != nil {
+		return nil, err
+	}
+	var factions []Faction
+	if err := decode(data, &factions); err != nil {
+		e.log.Error("Error parsing factions response", "error", err)
+		return nil, err
+	}
+	return factions, nil
+}
+
+// Races fetches the information about every race, cached for a day by default like Factions
+func (e *ESI) Races() ([]Race, error) {
+	data, err := e.GetPublic("universe/races")
+	if err != nil {
+		return nil, err
+	}
+	var races []Race
+	if err := decode(data, &races); err != nil {
+		e.log.Error("Error parsing races response", "error", err)
+		return nil, err
+	}
+	return races, nil
+}
+
+// StructureInfo is the public information about a player-owned structure, like a citadel
+type StructureInfo struct {
+	StructureID   int64    `json:"-"`
+	Name          string   `json:"name"`
+	OwnerID       int32    `json:"owner_id"`
+	SolarSystemID int32    `json:"solar_system_id"`
+	TypeID        int32    `json:"type_id"`
+	Position      Position `json:"position"`
+}
+
+// Structure fetches the information about the structure. ESI only gives it for structures
+// the character has docking access to; for the others, which ESI responds to with 403
+// Forbidden, ErrStructureAccessDenied is returned, wrapping the *ESIError, so they can be
+// skipped, like when going through the structures with markets. Needs the
+// esi-universe.read_structures.v1 scope.
+func (e *ESI) Structure(id int64) (*StructureInfo, error) {
+	data, err := e.Get("universe/structures/%d", id)
+	if err != nil {
+		var esiErr *ESIError
+		if errors.As(err, &esiErr) && esiErr.StatusCode == http.StatusForbidden {
+			return nil, fmt.Errorf("%w: %w", ErrStructureAccessDenied, err)
+		}
+		return nil, err
+	}
+	var info StructureInfo
+	if err := decode(data, &info); err != nil {
+		e.log.Error("Error parsing structure response", "structureID", id, "error", err)
+		return nil, err
+	}
+	info.StructureID = id
+	return &info, nil
+}
+
+// AllRegions fetches the information about every region, fetching the regions concurrently.
+// The map rarely changes, so it's cached for a day by default (see CacheTTLOverrides).
+func (e *ESI) AllRegions() ([]RegionInfo, error) {
+	var regions []RegionInfo
+	if err := e.getEveryID("universe/regions", &regions); err != nil {
+		return nil, err
+	}
+	return regions, nil
+}
+
+// AllConstellations fetches the information about every constellation, fetching the
+// constellations concurrently. The map rarely changes, so it's cached for a day by default.
+func (e *ESI) AllConstellations() ([]ConstellationInfo, error) {
+	var constellations []ConstellationInfo
+	if err := e.getEveryID("universe/constellations", &constellations); err != nil {
+		return nil, err
+	}
+	return constellations, nil
+}
+
+// AllSystems fetches the information about every solar system, fetching the systems
+// concurrently. There are thousands of systems, so the first call takes a while; the map
+// rarely changes, so it's cached for a day by default.
+func (e *ESI) AllSystems() ([]SystemInfo, error) {
+	var systems []SystemInfo
+	if err := e.getEveryID("universe/systems", &systems); err != nil {
+		return nil, err
+	}
+	return systems, nil
+}
+
+// getEveryID fetches the list of IDs at the path, then the details of each ID at
+// path/{id} concurrently, and decodes the details into v as an array
+func (e *ESI) getEveryID(path string, v interface{}) error {
+	list, err := e.Get(path)
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	if err := decode(list, &ids); err != nil {
+		e.log.Error("Error parsing ID list response", "path", path, "error", err)
+		return err
+	}
+	paths := make([]string, len(ids))
+	for i, id := range ids {
+		paths[i] = fmt.Sprintf("%s/%d", path, id)
+	}
+	responses, err := e.getAll(paths)
+	if err != nil {
+		return err
+	}
+	items := make([]interface{}, len(responses))
+	for i, response := range responses {
+		items[i] = response.Data()
+	}
+	details, err := gabs.Consume(items)
+	if err != nil {
+		return err
+	}
+	if err := decode(details, v); err != nil {
+		e.log.Error("Error parsing details response", "path", path, "error", err)
+		return err
+	}
+	return nil
+}
+
+// Route flags, for which systems Route prefers to travel through
+const (
+	RouteShortest = "shortest"
+	RouteSecure   = "secure"
+	RouteInsecure = "insecure"
+)
+
+// Route fetches the systems on the route from the origin system to the destination,
+// including both. The flag is one of the Route* consts, or empty for ESI's default of
+// the shortest route. The route doesn't pass through any of the systems to avoid.
+func (e *ESI) Route(origin, destination int32, flag string, avoid []int32) ([]int32, error) {
+	data, err := e.Get("route/%d/%d", origin, destination, WithParams(routeParams(flag, avoid)))
+	if err != nil {
+		return nil, err
+	}
+	var systems []int32
+	if err := decode(data, &systems); err != nil {
+		e.log.Error("Error parsing route response", "origin", origin, "destination", destination, "error", err)
+		return nil, err
+	}
+	return systems, nil
+}
+
+// routeParams returns the query params for a route. ESI takes the systems to avoid
+// as one comma-separated list.
+func routeParams(flag string, avoid []int32) url.Values {
+	params := url.Values{}
+	if flag != "" {
+		params.Set("flag", flag)
+	}
+	if len(avoid) > 0 {
+		ids := make([]string, len(avoid))
+		for i, id := range avoid {
+			ids[i] = strconv.Itoa(int(id))
+		}
+		params.Set("avoid", strings.Join(ids, ","))
+	}
+	return params
+}
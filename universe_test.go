@@ -0,0 +1,201 @@
+package goesi
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAllRegions(t *testing.T) {
+	var calls int32
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/universe/regions/"):
+			return stubResponse(200, `[10000001, 10000002]`), nil
+		case strings.HasSuffix(req.URL.Path, "/universe/regions/10000001/"):
+			return stubResponse(200, `{"region_id": 10000001, "name": "Derelik", "constellations": [20000001]}`), nil
+		case strings.HasSuffix(req.URL.Path, "/universe/regions/10000002/"):
+			return stubResponse(200, `{"region_id": 10000002, "name": "The Forge", "constellations": [20000020, 20000021]}`), nil
+		}
+		t.Fatalf("Unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	for i := 0; i < 2; i++ {
+		regions, err := e.AllRegions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(regions) != 2 || regions[0].Name != "Derelik" || regions[1].RegionID != 10000002 || len(regions[1].Constellations) != 2 {
+			t.Fatalf("Unexpected regions: %+v", regions)
+		}
+	}
+	if calls != 3 {
+		t.Fatalf("Expected the regions to be cached by default, got %d calls", calls)
+	}
+}
+
+func TestRoute(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(req.URL.Path, "/route/30000142/30002187/") {
+			t.Fatalf("Unexpected request to %s", req.URL)
+		}
+		if query := req.URL.Query(); query.Get("flag") != RouteSecure || query.Get("avoid") != "30000144,30002053" {
+			t.Fatalf("Unexpected params %v", query)
+		}
+		return stubResponse(200, `[30000142, 30000144, 30002187]`), nil
+	})
+	systems, err := e.Route(30000142, 30002187, RouteSecure, []int32{30000144, 30002053})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(systems) != 3 || systems[0] != 30000142 || systems[2] != 30002187 {
+		t.Fatalf("Unexpected route %v", systems)
+	}
+}
+
+func TestRouteParams(t *testing.T) {
+	if params := routeParams("", nil); len(params) != 0 {
+		t.Fatalf("Expected no params, got %v", params)
+	}
+	if encoded := routeParams(RouteShortest, []int32{1, 2}).Encode(); encoded != "avoid=1%2C2&flag=shortest" {
+		t.Fatalf("Unexpected params '%s'", encoded)
+	}
+}
+
+func TestTypes(t *testing.T) {
+	var calls int32
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/universe/types/34/"):
+			return stubResponse(200, `{"type_id": 34, "name": "Tritanium", "volume": 0.01, "published": true}`), nil
+		case strings.HasSuffix(req.URL.Path, "/universe/types/587/"):
+			return stubResponse(200, `{"type_id": 587, "name": "Rifter", "group_id": 25}`), nil
+		}
+		t.Fatalf("Unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	for i := 0; i < 2; i++ {
+		types, err := e.Types([]int32{34, 587})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(types) != 2 || types[34].Name != "Tritanium" || types[34].Volume != 0.01 || types[587].GroupID != 25 {
+			t.Fatalf("Unexpected types: %+v", types)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("Expected the types to be cached by default, got %d calls", calls)
+	}
+}
+
+func TestResolveAndFetch(t *testing.T) {
+	tests := []struct {
+		name     string
+		resolved string
+		expected error
+		fetched  string
+	}{
+		{"not found", `{}`, ErrNameNotFound, ""},
+		{"one match", `{"characters": [{"id": 90000001, "name": "Goesi Pilot"}]}`, nil, "/characters/90000001/"},
+		{"exact match among several", `{"characters": [{"id": 90000002, "name": "Goesi Pilot II"}, {"id": 90000001, "name": "goesi pilot"}]}`, nil, "/characters/90000001/"},
+		{"ambiguous", `{"characters": [{"id": 90000002, "name": "Goesi Pilot II"}, {"id": 90000003, "name": "Goesi Pilot III"}]}`, ErrAmbiguousName, ""},
+	}
+	for _, test := range tests {
+		var fetched []string
+		e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+			if req.Method == "POST" {
+				if !strings.HasSuffix(req.URL.Path, "/universe/ids/") {
+					t.Fatalf("%s: unexpected request to %s", test.name, req.URL)
+				}
+				return stubResponse(200, test.resolved), nil
+			}
+			fetched = append(fetched, req.URL.Path)
+			return stubResponse(200, `{"name": "Goesi Pilot"}`), nil
+		})
+		data, err := e.ResolveAndFetch("Goesi Pilot", "characters")
+		if test.expected != nil {
+			if !errors.Is(err, test.expected) || len(fetched) != 0 {
+				t.Fatalf("%s: expected %v without fetching, got %v after fetching %q", test.name, test.expected, err, fetched)
+			}
+			continue
+		}
+		if err != nil || data == nil {
+			t.Fatalf("%s: unexpected error %v", test.name, err)
+		}
+		if len(fetched) != 1 || !strings.HasSuffix(fetched[0], test.fetched) {
+			t.Fatalf("%s: expected %s to be fetched, got %q", test.name, test.fetched, fetched)
+		}
+	}
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		t.Fatalf("Unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	if _, err := e.ResolveAndFetch("Jita", "planets"); !errors.Is(err, ErrUnsupportedCategory) {
+		t.Fatalf("Expected ErrUnsupportedCategory, got %v", err)
+	}
+}
+
+func TestStructure(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/universe/structures/1021975535893/"):
+			return stubResponse(200, `{"name": "Perimeter - Tranquility Trading Tower", "owner_id": 98000001, "solar_system_id": 30000144, "type_id": 35834, "position": {"x": 1.5, "y": 2, "z": 3}}`), nil
+		case strings.HasSuffix(req.URL.Path, "/universe/structures/1021975535894/"):
+			return stubResponse(403, `{"error": "Forbidden"}`), nil
+		}
+		return stubResponse(404, `{"error": "Structure not found"}`), nil
+	})
+	e.AccessToken = "token"
+	info, err := e.Structure(1021975535893)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.StructureID != 1021975535893 || info.SolarSystemID != 30000144 || info.Position.X != 1.5 {
+		t.Fatalf("Unexpected structure: %+v", info)
+	}
+	var esiErr *ESIError
+	if _, err := e.Structure(1021975535894); !errors.Is(err, ErrStructureAccessDenied) || !errors.As(err, &esiErr) {
+		t.Fatalf("Expected ErrStructureAccessDenied wrapping the *ESIError, got %v", err)
+	}
+	if _, err := e.Structure(1); errors.Is(err, ErrStructureAccessDenied) || !errors.As(err, &esiErr) || esiErr.StatusCode != 404 {
+		t.Fatalf("Expected just the *ESIError for a missing structure, got %v", err)
+	}
+}
+
+func TestFactionsAndRaces(t *testing.T) {
+	var calls int32
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/universe/factions/"):
+			return stubResponse(200, `[{"faction_id": 500001, "name": "Caldari State", "corporation_id": 1000035, "militia_corporation_id": 1000180, "solar_system_id": 30000145, "size_factor": 5, "station_count": 1503, "station_system_count": 503, "is_unique": true}]`), nil
+		case strings.HasSuffix(req.URL.Path, "/universe/races/"):
+			return stubResponse(200, `[{"race_id": 1, "name": "Caldari", "description": "Founded on the tenets of patriotism and hard work", "alliance_id": 500001}]`), nil
+		}
+		t.Fatalf("Unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	for i := 0; i < 2; i++ {
+		factions, err := e.Factions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(factions) != 1 || factions[0].Name != "Caldari State" || factions[0].MilitiaCorporationID != 1000180 || !factions[0].IsUnique {
+			t.Fatalf("Unexpected factions: %+v", factions)
+		}
+		races, err := e.Races()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(races) != 1 || races[0].RaceID != 1 || races[0].AllianceID != 500001 {
+			t.Fatalf("Unexpected races: %+v", races)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("Expected the factions and races to be cached by default, got %d calls", calls)
+	}
+}
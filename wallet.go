@@ -0,0 +1,61 @@
+package goesi
+
+import (
+	"fmt"
+	"time"
+)
+
+// JournalEntry is an entry in a character's wallet journal
+type JournalEntry struct {
+	ID            int64     `json:"id"`
+	Date          time.Time `json:"date"`
+	RefType       string    `json:"ref_type"`
+	Amount        float64   `json:"amount"`
+	Balance       float64   `json:"balance"`
+	Description   string    `json:"description"`
+	Reason        string    `json:"reason"`
+	FirstPartyID  int32     `json:"first_party_id"`
+	SecondPartyID int32     `json:"second_party_id"`
+	ContextID     int64     `json:"context_id"`
+	ContextIDType string    `json:"context_id_type"`
+	Tax           float64   `json:"tax"`
+	TaxReceiverID int32     `json:"tax_receiver_id"`
+}
+
+// UnmarshalJSON decodes the journal entry, parsing its date with ParseESITime
+func (j *JournalEntry) UnmarshalJSON(data []byte) error {
+	type journalEntry JournalEntry
+	return decodeWithTimes(data, (*journalEntry)(j), map[string]*time.Time{
+		"date": &j.Date,
+	})
+}
+
+// WalletBalance fetches the character's wallet balance in ISK. The access token needs
+// the esi-wallet.read_character_wallet.v1 scope.
+func (e *ESI) WalletBalance(characterID int32) (float64, error) {
+	data, err := e.Get("characters/%d/wallet", characterID)
+	if err != nil {
+		return 0, err
+	}
+	balance, ok := data.Data().(float64)
+	if !ok {
+		e.log.Error("Error parsing wallet response", "characterID", characterID, "body", data.String())
+		return 0, fmt.Errorf("Expected the wallet balance to be a number, got '%s'", data)
+	}
+	return balance, nil
+}
+
+// WalletJournal fetches all pages of the character's wallet journal, newest first.
+// The access token needs the esi-wallet.read_character_wallet.v1 scope.
+func (e *ESI) WalletJournal(characterID int32) ([]JournalEntry, error) {
+	data, err := e.GetAllPages("characters/%d/wallet/journal", characterID)
+	if err != nil {
+		return nil, err
+	}
+	var entries []JournalEntry
+	if err := decode(data, &entries); err != nil {
+		e.log.Error("Error parsing wallet journal response", "characterID", characterID, "error", err)
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,37 @@
+package goesi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWallet(t *testing.T) {
+	e := newStubbedESI(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/wallet/") {
+			return stubResponse(200, `29500.01`), nil
+		}
+		switch req.URL.Query().Get("page") {
+		case "1":
+			return stubResponse(200, `[{"id": 2, "date": "2020-01-02T03:04:05Z", "ref_type": "bounty_prizes", "amount": 1500.5, "balance": 29500.01, "reason": "23:1"}]`, PagesHeader, "2"), nil
+		case "2":
+			return stubResponse(200, `[{"id": 1, "ref_type": "player_donation", "amount": -100, "balance": 28000}]`, PagesHeader, "2"), nil
+		}
+		t.Fatalf("Unexpected request to %s", req.URL)
+		return nil, nil
+	})
+	balance, err := e.WalletBalance(90000001)
+	if err != nil || balance != 29500.01 {
+		t.Fatalf("Expected a balance of 29500.01, got %f %v", balance, err)
+	}
+	entries, err := e.WalletJournal(90000001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].RefType != "bounty_prizes" || entries[0].Amount != 1500.5 || entries[0].Reason != "23:1" || entries[0].Date.Year() != 2020 {
+		t.Fatalf("Unexpected first entry: %+v", entries)
+	}
+	if entries[1].Amount != -100 || entries[1].Balance != 28000 {
+		t.Fatalf("Unexpected second entry: %+v", entries[1])
+	}
+}